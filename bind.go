@@ -0,0 +1,205 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+
+	ping "github.com/prometheus-community/pro-bing"
+)
+
+// DSCP code points by name, per RFC 2474, RFC 2597, RFC 3246 and RFC 5865
+var dscpNames = map[string]int{
+	"BE":   0,
+	"CS0":  0,
+	"CS1":  8,
+	"AF11": 10,
+	"AF12": 12,
+	"AF13": 14,
+	"CS2":  16,
+	"AF21": 18,
+	"AF22": 20,
+	"AF23": 22,
+	"CS3":  24,
+	"AF31": 26,
+	"AF32": 28,
+	"AF33": 30,
+	"CS4":  32,
+	"AF41": 34,
+	"AF42": 36,
+	"AF43": 38,
+	"CS5":  40,
+	"VA":   44,
+	"EF":   46,
+	"CS6":  48,
+	"CS7":  56,
+}
+
+// Binding holds how probes leave this host: the address and device they are sent
+// from, and how they are marked for QoS and policy routing.
+type Binding struct {
+	Source       string
+	Interface    string
+	TrafficClass int
+	Mark         int
+}
+
+// splitBinding takes an interface name or address, as given to -I by iputils, and returns it as one or the other.
+func splitBinding(binding string) (string, string) {
+	if net.ParseIP(binding) != nil {
+		return binding, ""
+	}
+
+	return "", binding
+}
+
+// sourceAddress returns the address probes should be sent from, given either by
+// --source or, as with iputils' -I, by passing an address to --interface.
+func sourceAddress() string {
+	if source != "" {
+		return source
+	}
+
+	address, _ := splitBinding(iface)
+
+	return address
+}
+
+// interfaceName returns the device probes should be sent through, if --interface names one.
+func interfaceName() string {
+	_, name := splitBinding(iface)
+
+	return name
+}
+
+// parseDSCP accepts a DSCP code point by name (e.g. EF or AF41) or number.
+func parseDSCP(value string) (int, error) {
+	point, ok := dscpNames[strings.ToUpper(value)]
+	if ok {
+		return point, nil
+	}
+
+	point, err := strconv.Atoi(value)
+	if err != nil || point < 0 || point > 63 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidDSCP, value)
+	}
+
+	return point, nil
+}
+
+func validDSCP() bool {
+	for _, value := range dscp {
+		_, err := parseDSCP(value)
+		if err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// trafficClass returns the TOS byte (or IPv6 traffic class) set by --tos, or by a single --dscp.
+func trafficClass() int {
+	if len(dscp) == 1 {
+		point, _ := parseDSCP(dscp[0])
+
+		return point << 2
+	}
+
+	return tos
+}
+
+// globalBinding returns the binding given on the command line.
+func globalBinding() Binding {
+	return Binding{
+		Source:       sourceAddress(),
+		Interface:    interfaceName(),
+		TrafficClass: trafficClass(),
+		Mark:         mark,
+	}
+}
+
+func (b Binding) apply(pinger *ping.Pinger) {
+	pinger.Source = b.Source
+	pinger.InterfaceName = b.Interface
+	pinger.SetTrafficClass(uint8(b.TrafficClass))
+	pinger.SetMark(uint(b.Mark))
+}
+
+// control returns a socket control function that applies the binding to sockets
+// as they are created, before anything is sent, or nil if there is nothing to apply.
+func (b Binding) control() func(network, address string, c syscall.RawConn) error {
+	if b.Interface == "" && b.TrafficClass == 0 && b.Mark == 0 {
+		return nil
+	}
+
+	return func(network, address string, c syscall.RawConn) error {
+		return setSocketOptions(c, strings.HasSuffix(network, "6"), b)
+	}
+}
+
+// dialer returns a dialer for the given network ("tcp" or "udp") that connects according to the binding.
+func (b Binding) dialer(network string) *net.Dialer {
+	dialer := &net.Dialer{
+		Control: b.control(),
+	}
+
+	ip := net.ParseIP(b.Source)
+	if ip == nil {
+		return dialer
+	}
+
+	switch network {
+	case "udp":
+		dialer.LocalAddr = &net.UDPAddr{IP: ip}
+	default:
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+
+	return dialer
+}
+
+// banner describes how probes are sent, for the start of a run.
+func (b Binding) banner(colors *Colors) string {
+	var banner string
+
+	if b.Source != "" {
+		banner += " " + colors.Blue.Sprint(b.Source)
+	}
+
+	if b.Interface != "" {
+		banner += " " + colors.Blue.Sprint(b.Interface)
+	}
+
+	if banner != "" {
+		banner = " from" + banner
+	}
+
+	if b.TrafficClass != 0 {
+		banner += " tos " + colors.Blue.Sprintf("0x%02x", b.TrafficClass)
+	}
+
+	if b.Mark != 0 {
+		banner += " mark " + colors.Blue.Sprint(b.Mark)
+	}
+
+	return banner
+}
+
+// validSource reports whether --source, and --interface if it was given an address, make sense together.
+func validSource() bool {
+	switch {
+	case source == "":
+		return true
+	case net.ParseIP(source) == nil:
+		return false
+	default:
+		return net.ParseIP(iface) == nil
+	}
+}
@@ -0,0 +1,40 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import "syscall"
+
+func setSocketOptions(c syscall.RawConn, v6 bool, b Binding) error {
+	var err error
+
+	controlErr := c.Control(func(fd uintptr) {
+		if b.Interface != "" {
+			err = syscall.SetsockoptString(int(fd), syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, b.Interface)
+			if err != nil {
+				return
+			}
+		}
+
+		if b.TrafficClass != 0 {
+			if v6 {
+				err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, b.TrafficClass)
+			} else {
+				err = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, b.TrafficClass)
+			}
+			if err != nil {
+				return
+			}
+		}
+
+		if b.Mark != 0 {
+			err = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_MARK, b.Mark)
+		}
+	})
+	if controlErr != nil {
+		return controlErr
+	}
+
+	return err
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import "syscall"
+
+func setSocketOptions(c syscall.RawConn, v6 bool, b Binding) error {
+	return ErrSocketOptionsUnsupported
+}
@@ -0,0 +1,250 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// probeCount returns the number of probes --count asks for, which with --burst counts bursts rather than probes.
+func probeCount() int {
+	return count * burst
+}
+
+// perProbe reports whether probes are reported one line at a time, rather than as progress or whole bursts.
+func perProbe() bool {
+	return !flood && burst == 1
+}
+
+// A burst of probes, once every one of them has been answered or lost
+type BurstResult struct {
+	Burst    uint64
+	Sent     int
+	Received int
+	Loss     float64
+	Min      time.Duration
+	Median   time.Duration
+	Max      time.Duration
+	Spread   time.Duration
+}
+
+type pendingBurst struct {
+	settled int
+	rtts    []time.Duration
+}
+
+// Bursts gathers a target's probes into the bursts they were sent in, in the style of SmokePing,
+// and keeps every completed burst's loss, median and spread for the final summary.
+type Bursts struct {
+	mu      sync.Mutex
+	size    int
+	pending map[uint64]*pendingBurst
+
+	count   int
+	lossy   int
+	worst   float64
+	medians []time.Duration
+	spreads []time.Duration
+}
+
+func newBursts(size int) *Bursts {
+	return &Bursts{
+		size:    size,
+		pending: make(map[uint64]*pendingBurst),
+	}
+}
+
+// settle records probe seq as answered after rtt, or lost, and returns its burst if that was the last
+// of the burst still outstanding.
+func (b *Bursts) settle(seq uint64, rtt time.Duration, lost bool) *BurstResult {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	number := seq / uint64(b.size)
+
+	p, ok := b.pending[number]
+	if !ok {
+		p = &pendingBurst{}
+
+		b.pending[number] = p
+	}
+
+	p.settled++
+
+	if !lost {
+		p.rtts = append(p.rtts, rtt)
+	}
+
+	if p.settled < b.size {
+		return nil
+	}
+
+	delete(b.pending, number)
+
+	result := &BurstResult{
+		Burst:    number,
+		Sent:     b.size,
+		Received: len(p.rtts),
+		Loss:     float64(b.size-len(p.rtts)) / float64(b.size) * 100,
+	}
+
+	if len(p.rtts) > 0 {
+		slices.Sort(p.rtts)
+
+		result.Min = p.rtts[0]
+		result.Median = median(p.rtts)
+		result.Max = p.rtts[len(p.rtts)-1]
+		result.Spread = result.Max - result.Min
+
+		b.medians = append(b.medians, result.Median)
+		b.spreads = append(b.spreads, result.Spread)
+	}
+
+	b.count++
+
+	if result.Loss > 0 {
+		b.lossy++
+	}
+
+	b.worst = max(b.worst, result.Loss)
+
+	return result
+}
+
+// A summary of every burst completed so far
+type BurstSummary struct {
+	Bursts     int
+	Lossy      int
+	WorstLoss  float64
+	MedianP50  time.Duration
+	MedianP95  time.Duration
+	MedianMax  time.Duration
+	SpreadP50  time.Duration
+	SpreadP95  time.Duration
+	SpreadMax  time.Duration
+	AnyReplies bool
+}
+
+func (b *Bursts) Summary() BurstSummary {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	summary := BurstSummary{
+		Bursts:    b.count,
+		Lossy:     b.lossy,
+		WorstLoss: b.worst,
+	}
+
+	if len(b.medians) == 0 {
+		return summary
+	}
+
+	medians := slices.Sorted(slices.Values(b.medians))
+	spreads := slices.Sorted(slices.Values(b.spreads))
+
+	summary.AnyReplies = true
+	summary.MedianP50, summary.MedianP95, summary.MedianMax = percentile(medians, 50), percentile(medians, 95), medians[len(medians)-1]
+	summary.SpreadP50, summary.SpreadP95, summary.SpreadMax = percentile(spreads, 50), percentile(spreads, 95), spreads[len(spreads)-1]
+
+	return summary
+}
+
+// median returns the middle of sorted, or the mean of the two middle values if there is an even number of them.
+func median(sorted []time.Duration) time.Duration {
+	mid := len(sorted) / 2
+
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	return sorted[mid]
+}
+
+// percentile returns the nearest-rank pth percentile of sorted, which must not be empty.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+
+	return sorted[max(rank, 1)-1]
+}
+
+// settleBurst counts probe seq towards its burst, and reports the burst once it is complete.
+func settleBurst(seq uint64, rtt time.Duration, lost bool, target *Target) error {
+	if target.bursts == nil {
+		return nil
+	}
+
+	result := target.bursts.settle(seq, rtt, lost)
+	if result == nil {
+		return nil
+	}
+
+	return showBurst(result, target)
+}
+
+func showBurst(result *BurstResult, target *Target) error {
+	colors := target.colors
+
+	if quiet {
+		return nil
+	}
+
+	switch format {
+	case "json":
+		return target.out.Event(newJSONBurst(result, target))
+	case "csv":
+		return target.out.Record(newCSVBurst(result, target))
+	}
+
+	if result.Received == 0 {
+		return target.out.Printf("%s%s", linePrefix(target),
+			colors.Red.Sprintf("Burst %d lost: 0 of %d probes answered.\n", result.Burst, result.Sent))
+	}
+
+	return target.out.Printf("%sburst=%s sent=%s received=%s loss=%s min=%s median=%s max=%s spread=%s\n",
+		linePrefix(target),
+		colors.Blue.Sprint(result.Burst),
+		colors.Blue.Sprint(result.Sent),
+		colors.Blue.Sprint(result.Received),
+		highlightPacketLoss(result.Loss, colors),
+		highlightLongRTT(result.Min.Round(time.Microsecond), target.MaxRtt, colors, true),
+		highlightLongRTT(result.Median.Round(time.Microsecond), target.MaxRtt, colors, true),
+		highlightLongRTT(result.Max.Round(time.Microsecond), target.MaxRtt, colors, false),
+		colors.Blue.Sprint(result.Spread.Round(time.Microsecond)))
+}
+
+// showBurstStatistics describes the spread of loss, median round-trip time and jitter across target's bursts.
+func showBurstStatistics(target *Target) string {
+	colors := target.colors
+
+	summary := target.bursts.Summary()
+
+	var s strings.Builder
+
+	s.WriteString(fmt.Sprintf("%s bursts of %s, %s with loss (worst %s)\n",
+		colors.Blue.Sprint(summary.Bursts),
+		colors.Blue.Sprint(burst),
+		highlightCount(summary.Lossy, colors),
+		highlightPacketLoss(summary.WorstLoss, colors)))
+
+	if !summary.AnyReplies {
+		return s.String()
+	}
+
+	s.WriteString(fmt.Sprintf("burst median p50/p95/max = %s/%s/%s\n",
+		highlightLongRTT(summary.MedianP50.Round(time.Microsecond), target.MaxRtt, colors, true),
+		highlightLongRTT(summary.MedianP95.Round(time.Microsecond), target.MaxRtt, colors, true),
+		highlightLongRTT(summary.MedianMax.Round(time.Microsecond), target.MaxRtt, colors, true)))
+
+	s.WriteString(fmt.Sprintf("burst spread p50/p95/max = %s/%s/%s\n",
+		colors.Blue.Sprint(summary.SpreadP50.Round(time.Microsecond)),
+		colors.Blue.Sprint(summary.SpreadP95.Round(time.Microsecond)),
+		colors.Blue.Sprint(summary.SpreadMax.Round(time.Microsecond))))
+
+	return s.String()
+}
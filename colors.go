@@ -15,9 +15,32 @@ type Colors struct {
 	Blue  *color.Color
 	Green *color.Color
 	Grey  *color.Color
+	Label *color.Color
 	Red   *color.Color
 }
 
+// Rotated through so each target's label stands out from its neighbours
+var labelColors = []color.Attribute{
+	color.FgCyan,
+	color.FgMagenta,
+	color.FgYellow,
+	color.FgHiBlue,
+	color.FgHiGreen,
+	color.FgHiMagenta,
+	color.FgHiCyan,
+	color.FgHiYellow,
+}
+
+func newColors(index int) *Colors {
+	return &Colors{
+		Blue:  color.New(color.FgBlue),
+		Green: color.New(color.FgGreen),
+		Grey:  color.New(color.FgHiBlack),
+		Label: color.New(labelColors[index%len(labelColors)]),
+		Red:   color.New(color.FgRed),
+	}
+}
+
 func highlightPacketLoss(packetLoss float64, colors *Colors) string {
 	if packetLoss != 0.0 {
 		return colors.Red.Sprintf("%.3f%%", packetLoss)
@@ -26,7 +49,15 @@ func highlightPacketLoss(packetLoss float64, colors *Colors) string {
 	}
 }
 
-func highlightLongRTT(packetRTT time.Duration, colors *Colors, isEnding bool) string {
+func highlightCount(count int, colors *Colors) string {
+	if count != 0 {
+		return colors.Red.Sprintf("%d", count)
+	} else {
+		return colors.Blue.Sprintf("%d", count)
+	}
+}
+
+func highlightLongRTT(packetRTT, maxRtt time.Duration, colors *Colors, isEnding bool) string {
 	switch {
 	case packetRTT > maxRtt && beep && !isEnding:
 		fmt.Println("\a")
@@ -38,3 +69,16 @@ func highlightLongRTT(packetRTT time.Duration, colors *Colors, isEnding bool) st
 		return colors.Blue.Sprintf("%s", packetRTT)
 	}
 }
+
+func highlightDetail(value any, warn bool, colors *Colors) string {
+	switch {
+	case warn && beep:
+		fmt.Println("\a")
+
+		return colors.Red.Sprint(value)
+	case warn:
+		return colors.Red.Sprint(value)
+	default:
+		return colors.Blue.Sprint(value)
+	}
+}
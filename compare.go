@@ -0,0 +1,659 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// The outcome of one probe in a comparison window
+type variantResult struct {
+	settled     bool
+	lost        bool
+	unavailable bool
+	rtt         time.Duration
+}
+
+// Comparison lines up the probes sent to one host as each of its variants, i.e. at each
+// of its addresses, over each --via path, with each --dscp class and over each address
+// family, so that they can be compared window by window, by sequence number.
+type Comparison struct {
+	mu      sync.Mutex
+	host    string
+	targets []*Target
+	windows map[uint64][]variantResult
+	stats   []rttStats
+	wins    []int
+	total   int
+
+	// Consecutive windows in which each variant was lost while another got a reply
+	stalled []int
+
+	// Variants that are never probed, because their host has no address in their family
+	unavailable []bool
+}
+
+// variantOption is one of the ways a target can be probed along some dimension, e.g. over one --via path.
+type variantOption struct {
+	label string
+	apply func(*Target)
+}
+
+// variantDimensions returns the ways targets should be varied, according to --via, --dscp and --dual-stack.
+func variantDimensions() [][]variantOption {
+	var dimensions [][]variantOption
+
+	if len(via) > 0 {
+		var options []variantOption
+
+		for _, path := range via {
+			options = append(options, variantOption{path, func(target *Target) {
+				target.Source, target.Interface = splitBinding(path)
+			}})
+		}
+
+		dimensions = append(dimensions, options)
+	}
+
+	// A single class is just a setting, applied to every target
+	if len(dscp) > 1 {
+		var options []variantOption
+
+		for _, class := range dscp {
+			point, _ := parseDSCP(class)
+
+			options = append(options, variantOption{strings.ToUpper(class), func(target *Target) {
+				target.TrafficClass = point << 2
+			}})
+		}
+
+		dimensions = append(dimensions, options)
+	}
+
+	// Kept last, so that the other labels identify which variants to weigh the families up between
+	if dualStack {
+		dimensions = append(dimensions, []variantOption{
+			{"v4", func(target *Target) { target.Network = "ip4" }},
+			{"v6", func(target *Target) { target.Network = "ip6" }},
+		})
+	}
+
+	return dimensions
+}
+
+// addressOptions returns one option per address target's host resolves to, for --all-addresses,
+// or nil if it is an address already.
+func addressOptions(target *Target) ([]variantOption, error) {
+	if net.ParseIP(target.Host) != nil {
+		return nil, nil
+	}
+
+	var ips []net.IP
+
+	err := waitForDNS(target.Host, func() error {
+		var err error
+
+		ips, err = lookupIPs(target.Host, target.Network)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var options []variantOption
+
+	seen := make(map[string]bool)
+
+	for _, ip := range ips {
+		address := ip.String()
+		if seen[address] {
+			continue
+		}
+
+		seen[address] = true
+
+		options = append(options, variantOption{address, func(target *Target) {
+			target.Address = address
+		}})
+	}
+
+	return options, nil
+}
+
+// familyNames are the address families --dual-stack compares, by network
+var familyNames = map[string]string{"ip4": "IPv4", "ip6": "IPv6"}
+
+func familyOf(ip net.IP) string {
+	if ip.To4() != nil {
+		return "ip4"
+	}
+
+	return "ip6"
+}
+
+// addressFamilies returns the networks, of ip4 and ip6, that target's host has an address on.
+func addressFamilies(target *Target) (map[string]bool, error) {
+	ip := net.ParseIP(target.Host)
+	if ip != nil {
+		return map[string]bool{familyOf(ip): true}, nil
+	}
+
+	var ips []net.IP
+
+	err := waitForDNS(target.Host, func() error {
+		var err error
+
+		ips, err = lookupIPs(target.Host, "ip")
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	families := make(map[string]bool)
+
+	for _, ip := range ips {
+		families[familyOf(ip)] = true
+	}
+
+	return families, nil
+}
+
+// combine returns every way of picking one option from each dimension, with the last dimension varying fastest.
+func combine(dimensions [][]variantOption) [][]variantOption {
+	combinations := [][]variantOption{nil}
+
+	for _, options := range dimensions {
+		var extended [][]variantOption
+
+		for _, combination := range combinations {
+			for _, option := range options {
+				extended = append(extended, append(slices.Clip(combination), option))
+			}
+		}
+
+		combinations = extended
+	}
+
+	return combinations
+}
+
+func newComparison(host string, variants int) *Comparison {
+	return &Comparison{
+		host:        host,
+		windows:     make(map[uint64][]variantResult),
+		stats:       make([]rttStats, variants),
+		wins:        make([]int, variants),
+		stalled:     make([]int, variants),
+		unavailable: make([]bool, variants),
+	}
+}
+
+// lead returns the first variant actually being probed, whose output and colors the comparison shares.
+func (c *Comparison) lead() *Target {
+	for i, target := range c.targets {
+		if !c.unavailable[i] {
+			return target
+		}
+	}
+
+	return c.targets[0]
+}
+
+// expandVariants replaces each target with one copy per combination of address, --via path,
+// --dscp class and address family, all sharing a Comparison when there is more than one.
+func expandVariants(targets []*Target) ([]*Target, error) {
+	shared := variantDimensions()
+
+	var expanded []*Target
+
+	for _, target := range targets {
+		dimensions := shared
+
+		if allAddresses {
+			addresses, err := addressOptions(target)
+			if err != nil {
+				return nil, err
+			}
+
+			if addresses != nil {
+				dimensions = append([][]variantOption{addresses}, shared...)
+			}
+		}
+
+		if len(dimensions) == 0 {
+			expanded = append(expanded, target)
+
+			continue
+		}
+
+		// A family the host has no address in is shown as unavailable, rather than probed
+		var families map[string]bool
+
+		if dualStack {
+			var err error
+
+			families, err = addressFamilies(target)
+			if err != nil {
+				return nil, err
+			}
+
+			for network, other := range map[string]string{"ip4": "ip6", "ip6": "ip4"} {
+				if !families[network] {
+					fmt.Fprintf(os.Stderr, "No %s address found for %s; probing it over %s only.\n", familyNames[network], target.Host, familyNames[other])
+				}
+			}
+		}
+
+		combinations := combine(dimensions)
+
+		comparison := newComparison(target.Name, len(combinations))
+
+		for _, combination := range combinations {
+			clone := *target
+
+			var labels []string
+
+			for _, option := range combination {
+				option.apply(&clone)
+
+				labels = append(labels, option.label)
+			}
+
+			clone.Variant = strings.Join(labels, "/")
+			clone.Name = target.Name + "@" + clone.Variant
+
+			if len(combinations) > 1 {
+				clone.comparison = comparison
+				clone.variant = len(comparison.targets)
+
+				comparison.targets = append(comparison.targets, &clone)
+			}
+
+			if families != nil && !families[clone.Network] {
+				comparison.unavailable[clone.variant] = true
+
+				continue
+			}
+
+			expanded = append(expanded, &clone)
+		}
+	}
+
+	return expanded, nil
+}
+
+func validVia() bool {
+	for _, path := range via {
+		if path == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// viaInterfaces reports whether any --via path is an interface, rather than an address.
+func viaInterfaces() bool {
+	for _, path := range via {
+		_, name := splitBinding(path)
+		if name != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// settle records the outcome of probe seq for one variant, and prints the window
+// once every variant has an outcome for it.
+func (c *Comparison) settle(target *Target, seq uint64, rtt time.Duration, lost bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := &c.stats[target.variant]
+
+	stats.sent++
+	if !lost {
+		stats.record(rtt)
+	}
+
+	window, ok := c.windows[seq]
+	if !ok {
+		window = make([]variantResult, len(c.targets))
+		c.windows[seq] = window
+
+		for i := range window {
+			if c.unavailable[i] {
+				window[i] = variantResult{settled: true, unavailable: true}
+			}
+		}
+	}
+
+	window[target.variant] = variantResult{settled: true, lost: lost, rtt: rtt}
+
+	for _, result := range window {
+		if !result.settled {
+			return nil
+		}
+	}
+
+	delete(c.windows, seq)
+
+	best := -1
+
+	for i, result := range window {
+		if !result.lost && !result.unavailable && (best == -1 || result.rtt < window[best].rtt) {
+			best = i
+		}
+	}
+
+	c.total++
+
+	if best != -1 {
+		c.wins[best]++
+	}
+
+	changes := c.track(window, best)
+
+	if format == "text" && !quiet && perProbe() {
+		err := c.show(seq, window, best)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes {
+		err := c.showChange(seq, change)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Windows a variant must go unanswered, while another variant is answered, before it is reported as having stopped responding
+const stalledWindows int = 3
+
+// A variant that has just stopped responding while others still are, or started responding again
+type variantChange struct {
+	variant int
+	stopped bool
+}
+
+// track keeps count of how long each variant has gone unanswered while the others have not,
+// and returns each variant that has just stopped or started responding again.
+func (c *Comparison) track(window []variantResult, best int) []variantChange {
+	// When every variant was lost, the problem is not specific to any of them
+	if best == -1 {
+		return nil
+	}
+
+	var changes []variantChange
+
+	for i, result := range window {
+		switch {
+		case result.unavailable:
+		case result.lost:
+			c.stalled[i]++
+
+			if c.stalled[i] == stalledWindows {
+				changes = append(changes, variantChange{variant: i, stopped: true})
+			}
+		case c.stalled[i] >= stalledWindows:
+			changes = append(changes, variantChange{variant: i})
+
+			c.stalled[i] = 0
+		default:
+			c.stalled[i] = 0
+		}
+	}
+
+	return changes
+}
+
+// showChange warns that a variant has stopped responding, or says that it is responding again, in every format.
+func (c *Comparison) showChange(seq uint64, change variantChange) error {
+	target := c.targets[change.variant]
+
+	switch format {
+	case "json":
+		return target.out.Event(newJSONVariant(change.stopped, seq, target))
+	case "csv":
+		// Rows in burst mode are whole bursts, which these have no place among
+		if burst > 1 {
+			return nil
+		}
+
+		return target.out.Record(newCSVVariant(change.stopped, seq, target))
+	}
+
+	if quiet {
+		return nil
+	}
+
+	lead := c.lead()
+	colors := lead.colors
+
+	var prefix string
+
+	if timestamp {
+		prefix = colors.Grey.Sprint(formatTimestamp(time.Now())) + " | "
+	}
+
+	prefix += colors.Green.Sprint(c.host) + " | "
+
+	if change.stopped {
+		return lead.out.Printf("%s%s", prefix, colors.Red.Sprintf("%s has stopped responding, while others are still answering.\n", target.Variant))
+	}
+
+	return lead.out.Printf("%s%s", prefix, colors.Green.Sprintf("%s is responding again.\n", target.Variant))
+}
+
+func (c *Comparison) show(seq uint64, window []variantResult, best int) error {
+	first := c.lead()
+	colors := first.colors
+
+	var s strings.Builder
+
+	if timestamp {
+		s.WriteString(colors.Grey.Sprint(formatTimestamp(time.Now())) + " | ")
+	}
+
+	s.WriteString(colors.Green.Sprint(c.host) + " | " + fmt.Sprintf("window=%s", colors.Blue.Sprint(seq)))
+
+	for i, result := range window {
+		target := c.targets[i]
+
+		if result.unavailable {
+			s.WriteString(" " + target.Variant + "=" + colors.Grey.Sprint("n/a"))
+
+			continue
+		}
+
+		s.WriteString(" " + target.colors.Label.Sprint(target.Variant) + "=")
+
+		switch {
+		case result.lost:
+			s.WriteString(colors.Red.Sprint("lost"))
+		case i == best:
+			s.WriteString(colors.Green.Sprint(result.rtt.Round(time.Microsecond)))
+		default:
+			s.WriteString(colors.Blue.Sprint(result.rtt.Round(time.Microsecond)))
+		}
+	}
+
+	if best == -1 {
+		s.WriteString(" best=" + colors.Red.Sprint("none"))
+	} else {
+		s.WriteString(" best=" + c.targets[best].colors.Label.Sprint(c.targets[best].Variant))
+	}
+
+	return first.out.Printf("%s\n", s.String())
+}
+
+// showComparisons summarises each variant of every host that was compared, and how often it was the fastest.
+func showComparisons(targets []*Target) string {
+	var s strings.Builder
+
+	w := tabwriter.NewWriter(&s, 0, 0, 2, ' ', 0)
+
+	seen := make(map[*Comparison]bool)
+
+	for _, target := range targets {
+		c := target.comparison
+		if c == nil || seen[c] {
+			continue
+		}
+
+		seen[c] = true
+
+		c.mu.Lock()
+
+		if len(seen) == 1 {
+			fmt.Fprintln(w, "HOST\tVARIANT\tSENT\tLOSS\tAVG\tJITTER\tBEST")
+		}
+
+		for i, variant := range c.targets {
+			if c.unavailable[i] {
+				fmt.Fprintf(w, "%s\t%s\t-\t-\t-\t-\t-\n", c.host, variant.Variant)
+
+				continue
+			}
+
+			stats := &c.stats[i]
+
+			best := 0.0
+			if c.total > 0 {
+				best = float64(c.wins[i]) / float64(c.total) * 100
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%d\t%.3f%%\t%s\t%s\t%.3f%%\n",
+				c.host,
+				variant.Variant,
+				stats.sent,
+				stats.loss(),
+				stats.avg().Round(time.Microsecond),
+				stats.jitter.Round(time.Microsecond),
+				best)
+		}
+
+		c.mu.Unlock()
+	}
+
+	w.Flush()
+
+	if len(seen) == 0 {
+		return ""
+	}
+
+	s.WriteString("\n")
+
+	if dualStack {
+		s.WriteString(showFamilies(targets))
+	}
+
+	return s.String()
+}
+
+// Happy Eyeballs gives IPv6 this much of a head start before trying IPv4 (the Connection Attempt Delay recommended by RFC 8305)
+const happyEyeballsDelay time.Duration = 250 * time.Millisecond
+
+// preferredFamily returns the address family a Happy Eyeballs client would be expected to settle on, and why if not IPv6.
+func preferredFamily(v4, v6 *rttStats) string {
+	switch {
+	case v4.recv == 0 && v6.recv == 0:
+		return "neither (no replies)"
+	case v6.recv == 0:
+		return "IPv4 (no replies over IPv6)"
+	case v4.recv == 0:
+		return "IPv6"
+	case v6.loss() > v4.loss():
+		return "IPv4 (more loss over IPv6)"
+	case v6.avg() > v4.avg()+happyEyeballsDelay:
+		return fmt.Sprintf("IPv4 (IPv6 over %s slower)", happyEyeballsDelay)
+	default:
+		return "IPv6"
+	}
+}
+
+// showFamilies weighs up IPv4 against IPv6 for each --dual-stack host, over each of its other variants.
+func showFamilies(targets []*Target) string {
+	var s strings.Builder
+
+	w := tabwriter.NewWriter(&s, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "HOST\tIPV4 LOSS\tIPV6 LOSS\tRTT DELTA\tPREFERRED")
+
+	seen := make(map[*Comparison]bool)
+
+	for _, target := range targets {
+		c := target.comparison
+		if c == nil || seen[c] {
+			continue
+		}
+
+		seen[c] = true
+
+		c.mu.Lock()
+
+		// Families vary fastest, so each IPv4 variant is directly followed by its IPv6 twin
+		for i := 0; i+1 < len(c.targets); i += 2 {
+			v4, v6 := &c.stats[i], &c.stats[i+1]
+
+			host := c.host
+
+			group := strings.TrimSuffix(strings.TrimSuffix(c.targets[i].Variant, "v4"), "/")
+			if group != "" {
+				host += "@" + group
+			}
+
+			delta := "-"
+			if v4.recv > 0 && v6.recv > 0 {
+				difference := (v6.avg() - v4.avg()).Round(time.Microsecond)
+
+				delta = difference.String()
+				if difference >= 0 {
+					delta = "+" + delta
+				}
+			}
+
+			v4Loss, v6Loss := fmt.Sprintf("%.3f%%", v4.loss()), fmt.Sprintf("%.3f%%", v6.loss())
+			preferred := preferredFamily(v4, v6)
+
+			switch {
+			case c.unavailable[i]:
+				v4Loss, preferred = "-", "IPv6 (no IPv4 address)"
+			case c.unavailable[i+1]:
+				v6Loss, preferred = "-", "IPv4 (no IPv6 address)"
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				host,
+				v4Loss,
+				v6Loss,
+				delta,
+				preferred)
+		}
+
+		c.mu.Unlock()
+	}
+
+	w.Flush()
+
+	s.WriteString("\n")
+
+	return s.String()
+}
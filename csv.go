@@ -0,0 +1,113 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"net/netip"
+	"strconv"
+	"time"
+
+	ping "github.com/prometheus-community/pro-bing"
+)
+
+var csvHeader = []string{"timestamp", "seq", "status", "rtt_us", "ttl", "bytes", "address", "host"}
+
+func newCSVReply(status string, pkt *ping.Packet, seq uint64, target *Target) []string {
+	var replyTTL, bytes string
+
+	// Only ICMP replies carry a TTL and payload worth reporting
+	if target.Protocol == ProtocolICMP {
+		replyTTL = strconv.Itoa(pkt.TTL)
+		bytes = strconv.Itoa(pkt.Nbytes - 8)
+	}
+
+	return []string{
+		time.Now().Format(time.RFC3339Nano),
+		strconv.FormatUint(seq, 10),
+		status,
+		strconv.FormatFloat(float64(pkt.Rtt.Nanoseconds())/1000, 'f', 3, 64),
+		replyTTL,
+		bytes,
+		pkt.IPAddr.String(),
+		target.Name,
+	}
+}
+
+func newCSVLost(seq uint64, target *Target) []string {
+	return []string{
+		time.Now().Format(time.RFC3339Nano),
+		strconv.FormatUint(seq, 10),
+		"lost",
+		"",
+		"",
+		"",
+		"",
+		target.Name,
+	}
+}
+
+func newCSVVariant(stopped bool, seq uint64, target *Target) []string {
+	status := "variant_up"
+	if stopped {
+		status = "variant_down"
+	}
+
+	return []string{
+		time.Now().Format(time.RFC3339Nano),
+		strconv.FormatUint(seq, 10),
+		status,
+		"",
+		"",
+		"",
+		target.address.Current().String(),
+		target.Name,
+	}
+}
+
+var csvBurstHeader = []string{"timestamp", "burst", "sent", "received", "loss_percent", "min_us", "median_us", "max_us", "spread_us", "host"}
+
+func newCSVBurst(result *BurstResult, target *Target) []string {
+	var minRtt, medianRtt, maxRtt, spread string
+
+	if result.Received > 0 {
+		minRtt = strconv.FormatFloat(float64(result.Min.Nanoseconds())/1000, 'f', 3, 64)
+		medianRtt = strconv.FormatFloat(float64(result.Median.Nanoseconds())/1000, 'f', 3, 64)
+		maxRtt = strconv.FormatFloat(float64(result.Max.Nanoseconds())/1000, 'f', 3, 64)
+		spread = strconv.FormatFloat(float64(result.Spread.Nanoseconds())/1000, 'f', 3, 64)
+	}
+
+	return []string{
+		time.Now().Format(time.RFC3339Nano),
+		strconv.FormatUint(result.Burst, 10),
+		strconv.Itoa(result.Sent),
+		strconv.Itoa(result.Received),
+		strconv.FormatFloat(result.Loss, 'f', 3, 64),
+		minRtt,
+		medianRtt,
+		maxRtt,
+		spread,
+		target.Name,
+	}
+}
+
+var csvSweepHeader = []string{"timestamp", "status", "address", "rtt_us", "ttl", "range"}
+
+func newCSVSweepHost(status string, addr netip.Addr, reply *sweepReply, sweep string) []string {
+	var rtt, replyTTL string
+
+	if reply != nil {
+		rtt = strconv.FormatFloat(float64(reply.rtt.Nanoseconds())/1000, 'f', 3, 64)
+		replyTTL = strconv.Itoa(reply.ttl)
+	}
+
+	return []string{
+		time.Now().Format(time.RFC3339Nano),
+		status,
+		addr.String(),
+		rtt,
+		replyTTL,
+		sweep,
+	}
+}
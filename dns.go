@@ -0,0 +1,159 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var dnsTypes = map[string]uint16{
+	"A":     1,
+	"NS":    2,
+	"CNAME": 5,
+	"SOA":   6,
+	"PTR":   12,
+	"MX":    15,
+	"TXT":   16,
+	"AAAA":  28,
+	"SRV":   33,
+	"ANY":   255,
+}
+
+var dnsRcodes = []string{"NOERROR", "FORMERR", "SERVFAIL", "NXDOMAIN", "NOTIMP", "REFUSED"}
+
+func validDNSType(name string) bool {
+	_, ok := dnsTypes[strings.ToUpper(name)]
+
+	return ok
+}
+
+func rcodeName(rcode int) string {
+	if rcode < len(dnsRcodes) {
+		return dnsRcodes[rcode]
+	}
+
+	return "RCODE" + strconv.Itoa(rcode)
+}
+
+// newDNSQuery builds a recursive query for a single name and type, in the class IN.
+func newDNSQuery(id uint16, name string, qtype uint16) ([]byte, error) {
+	query := binary.BigEndian.AppendUint16(nil, id)
+
+	// Recursion desired, one question
+	query = append(query, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00)
+
+	name = strings.TrimSuffix(name, ".")
+	if len(name) > 253 {
+		return nil, fmt.Errorf("query name %q is too long", name)
+	}
+
+	if name != "" {
+		for label := range strings.SplitSeq(name, ".") {
+			if len(label) == 0 || len(label) > 63 {
+				return nil, fmt.Errorf("query name %q has an invalid label", name)
+			}
+
+			query = append(query, byte(len(label)))
+			query = append(query, label...)
+		}
+	}
+
+	query = append(query, 0x00)
+	query = binary.BigEndian.AppendUint16(query, qtype)
+	query = binary.BigEndian.AppendUint16(query, 1)
+
+	return query, nil
+}
+
+// readDNSResponse waits for the response to query id, and returns its rcode and answer count.
+func readDNSResponse(conn net.Conn, id uint16) (int, int, error) {
+	buf := make([]byte, 65535)
+
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		// Anything too short, not a response, or for another query is stray traffic
+		if n < 12 || binary.BigEndian.Uint16(buf[0:2]) != id || buf[2]&0x80 == 0 {
+			continue
+		}
+
+		return int(buf[3] & 0x0f), int(binary.BigEndian.Uint16(buf[6:8])), nil
+	}
+}
+
+// newDNSProber times a query against a resolver over UDP; the rcode and answer
+// count of each response are logged alongside its round-trip time.
+func newDNSProber(target *Target) (*RequestProber, error) {
+	ipaddr, err := target.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	qtype := dnsTypes[strings.ToUpper(target.QueryType)]
+
+	// Fail on a bad name now, rather than on every query
+	_, err = newDNSQuery(0, target.QueryName, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := target.Binding.dialer("udp")
+
+	prober := newRequestProber(target, ipaddr, func(ctx context.Context, ipaddr *net.IPAddr) ([]Detail, error) {
+		id := uint16(rand.UintN(1 << 16))
+
+		query, err := newDNSQuery(id, target.QueryName, qtype)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(ipaddr.String(), strconv.Itoa(target.Port)))
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		deadline, ok := ctx.Deadline()
+		if ok {
+			conn.SetDeadline(deadline)
+		}
+
+		_, err = conn.Write(query)
+		if err != nil {
+			return nil, err
+		}
+
+		rcode, answers, err := readDNSResponse(conn, id)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Detail{
+			{Key: "rcode", Value: rcodeName(rcode)},
+			{Key: "answers", Value: answers},
+		}, nil
+	})
+
+	return prober, nil
+}
+
+// dnsTarget turns a --dns resolver into a dns:// target, bracketing IPv6 addresses so the port can be parsed.
+func dnsTarget(resolver string) string {
+	ip := net.ParseIP(resolver)
+	if ip != nil && ip.To4() == nil {
+		resolver = "[" + resolver + "]"
+	}
+
+	return "dns://" + resolver
+}
@@ -0,0 +1,360 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	ping "github.com/prometheus-community/pro-bing"
+	"golang.org/x/net/icmp"
+	netipv4 "golang.org/x/net/ipv4"
+	netipv6 "golang.org/x/net/ipv6"
+)
+
+// An echo reply, as read off the socket
+type echoReply struct {
+	seq      int
+	nbytes   int
+	ttl      int
+	received time.Time
+}
+
+// An echo request, as sent
+type echoRequest struct {
+	sent     time.Time
+	ipaddr   *net.IPAddr
+	answered bool
+}
+
+// EchoProber sends ICMP echo requests over a raw socket, reporting through the same
+// callbacks as a pro-bing Pinger. A Pinger only ever sends once per interval, so this
+// is what ICMP targets use when --flood, --preload or --burst asks for anything else.
+//
+// Replies are handled in the order they arrive, and like a Pinger's, are reported however
+// late they are, and however many times over; the tracker decides what to make of them.
+type EchoProber struct {
+	Count    int
+	Interval time.Duration
+	Timeout  time.Duration
+	Flood    bool
+	Preload  int
+
+	// Burst is the number of requests sent every interval, BurstSpacing apart
+	Burst        int
+	BurstSpacing time.Duration
+
+	OnSend          func(*ping.Packet)
+	OnRecv          func(*Reply)
+	OnDuplicateRecv func(*ping.Packet)
+	OnFail          func(*ping.Packet, error)
+	OnFinish        func(*ping.Statistics)
+
+	probeStats
+
+	conn net.PacketConn
+	p4   *netipv4.PacketConn
+	p6   *netipv6.PacketConn
+	v6   bool
+	id   int
+	size int
+
+	seq      int
+	requests map[int]*echoRequest
+	replies  chan echoReply
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newEchoProber(target *Target) (*EchoProber, error) {
+	ipaddr, err := target.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	v6 := ipaddr.IP.To4() == nil
+
+	privileged, err := icmpPrivileged(v6)
+	if err != nil {
+		return nil, err
+	}
+
+	if !privileged {
+		return nil, ErrPacingUnprivileged
+	}
+
+	network, address := "ip4:icmp", "0.0.0.0"
+	if v6 {
+		network, address = "ip6:ipv6-icmp", "::"
+	}
+
+	if target.Source != "" {
+		address = target.Source
+	}
+
+	listener := net.ListenConfig{Control: target.Binding.control()}
+
+	conn, err := listener.ListenPacket(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &EchoProber{
+		Count:        probeCount(),
+		Interval:     target.Interval,
+		Timeout:      timeout,
+		Flood:        flood,
+		Preload:      preload,
+		Burst:        burst,
+		BurstSpacing: burstSpacing,
+		probeStats: probeStats{
+			addr:   target.Host,
+			ipaddr: ipaddr,
+		},
+		conn:     conn,
+		v6:       v6,
+		id:       rand.IntN(1 << 16),
+		size:     target.Size,
+		requests: make(map[int]*echoRequest),
+		replies:  make(chan echoReply, 64),
+		done:     make(chan struct{}),
+	}
+
+	// Not every platform can report the TTL of replies, which are then just shown without one
+	if v6 {
+		p.p6 = netipv6.NewPacketConn(conn)
+
+		err = p.p6.SetHopLimit(ttl)
+		_ = p.p6.SetControlMessage(netipv6.FlagHopLimit, true)
+	} else {
+		p.p4 = netipv4.NewPacketConn(conn)
+
+		err = p.p4.SetTTL(ttl)
+		_ = p.p4.SetControlMessage(netipv4.FlagTTL, true)
+	}
+	if err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (p *EchoProber) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+}
+
+// send sends the next echo request, unless Count have been sent already.
+func (p *EchoProber) send() {
+	if p.Count != 0 && p.seq >= p.Count {
+		return
+	}
+
+	pkt := &ping.Packet{
+		Addr:   p.addr,
+		IPAddr: p.IPAddr(),
+		Seq:    p.seq % int(sequenceSpace),
+		Nbytes: p.size + 8,
+	}
+
+	p.seq++
+
+	var echo icmp.Type = netipv4.ICMPTypeEcho
+	if p.v6 {
+		echo = netipv6.ICMPTypeEchoRequest
+	}
+
+	msg := icmp.Message{
+		Type: echo,
+		Body: &icmp.Echo{
+			ID:   p.id,
+			Seq:  pkt.Seq,
+			Data: make([]byte, p.size),
+		},
+	}
+
+	packet, err := msg.Marshal(nil)
+	if err == nil {
+		// Replaces any request from a full sequence space ago, which is long since settled
+		p.requests[pkt.Seq] = &echoRequest{sent: time.Now(), ipaddr: pkt.IPAddr}
+
+		_, err = p.conn.WriteTo(packet, pkt.IPAddr)
+	}
+
+	p.updateSent()
+
+	if p.OnSend != nil {
+		p.OnSend(pkt)
+	}
+
+	if err != nil && p.OnFail != nil {
+		p.OnFail(pkt, err)
+	}
+}
+
+// receive passes every echo reply meant for this prober on to Run, until the socket is closed.
+func (p *EchoProber) receive() {
+	proto := 1
+	if p.v6 {
+		proto = 58
+	}
+
+	buf := make([]byte, 65536)
+
+	for {
+		var n, ttl int
+		var err error
+
+		if p.v6 {
+			var cm *netipv6.ControlMessage
+
+			n, cm, _, err = p.p6.ReadFrom(buf)
+			if cm != nil {
+				ttl = cm.HopLimit
+			}
+		} else {
+			var cm *netipv4.ControlMessage
+
+			n, cm, _, err = p.p4.ReadFrom(buf)
+			if cm != nil {
+				ttl = cm.TTL
+			}
+		}
+		if err != nil {
+			return
+		}
+
+		received := time.Now()
+
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || (msg.Type != netipv4.ICMPTypeEchoReply && msg.Type != netipv6.ICMPTypeEchoReply) {
+			continue
+		}
+
+		body, ok := msg.Body.(*icmp.Echo)
+		if !ok || body.ID != p.id {
+			continue
+		}
+
+		select {
+		case p.replies <- echoReply{seq: body.Seq, nbytes: n, ttl: ttl, received: received}:
+		case <-p.done:
+			return
+		}
+	}
+}
+
+func (p *EchoProber) handle(reply echoReply) {
+	request, ok := p.requests[reply.seq]
+	if !ok {
+		return
+	}
+
+	pkt := &ping.Packet{
+		Addr:   p.addr,
+		IPAddr: request.ipaddr,
+		Seq:    reply.seq,
+		Nbytes: reply.nbytes,
+		TTL:    reply.ttl,
+		Rtt:    reply.received.Sub(request.sent),
+	}
+
+	if request.answered {
+		p.updateDuplicates()
+
+		if p.OnDuplicateRecv != nil {
+			p.OnDuplicateRecv(pkt)
+		}
+
+		return
+	}
+
+	request.answered = true
+
+	p.updateStatistics(pkt)
+
+	if p.OnRecv != nil {
+		p.OnRecv(&Reply{Packet: pkt})
+	}
+}
+
+// Run sends echo requests, Preload of them at once to begin with and Burst of them every interval after,
+// until Timeout passes or Stop is called.
+func (p *EchoProber) Run() error {
+	defer p.conn.Close()
+	defer p.Stop()
+
+	go p.receive()
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(p.Timeout)
+	defer deadline.Stop()
+
+	// The rest of each burst follows its first request, BurstSpacing apart
+	spacing := time.NewTimer(p.BurstSpacing)
+	spacing.Stop()
+	defer spacing.Stop()
+
+	remaining := 0
+
+	startBurst := func() {
+		p.send()
+
+		remaining = p.Burst - 1
+		if remaining > 0 {
+			spacing.Reset(p.BurstSpacing)
+		}
+	}
+
+	for range p.Preload - 1 {
+		p.send()
+	}
+
+	startBurst()
+
+	for {
+		select {
+		case <-p.done:
+			return p.finish()
+		case <-deadline.C:
+			return p.finish()
+		case <-ticker.C:
+			startBurst()
+		case <-spacing.C:
+			p.send()
+
+			remaining--
+			if remaining > 0 {
+				spacing.Reset(p.BurstSpacing)
+			}
+		case reply := <-p.replies:
+			p.handle(reply)
+
+			// In flood mode, the interval is only how long to wait on a reply before sending the next regardless
+			if p.Flood {
+				p.send()
+
+				ticker.Reset(p.Interval)
+			}
+		}
+	}
+}
+
+func (p *EchoProber) finish() error {
+	if p.OnFinish != nil {
+		p.OnFinish(p.Statistics())
+	}
+
+	return nil
+}
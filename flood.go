@@ -0,0 +1,150 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	ping "github.com/prometheus-community/pro-bing"
+)
+
+const (
+	// With --flood and no --interval, probes still go out at least this often, as with iputils
+	floodInterval time.Duration = 10 * time.Millisecond
+
+	// How often --flood reports progress, in place of a line per probe
+	floodProgressInterval time.Duration = time.Second
+)
+
+// FloodMeter reports a flooded target's progress once a second, along with the rates it
+// achieved over that second. With a single target on a terminal, it also draws the indicator
+// ping -f does: a dot for every probe sent, rubbed out again by its reply.
+type FloodMeter struct {
+	sent     int
+	received int
+	at       time.Time
+	dots     bool
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newFloodMeter(labelled bool) *FloodMeter {
+	return &FloodMeter{
+		dots: format == "text" && !quiet && !labelled && isatty.IsTerminal(os.Stdout.Fd()),
+		done: make(chan struct{}),
+	}
+}
+
+// Sent adds a dot to the indicator for a probe just sent.
+func (m *FloodMeter) Sent() {
+	m.draw(".")
+}
+
+// Received rubs out a dot from the indicator for a reply just received.
+func (m *FloodMeter) Received() {
+	m.draw("\b \b")
+}
+
+func (m *FloodMeter) draw(s string) {
+	if !m.dots {
+		return
+	}
+
+	printMu.Lock()
+	defer printMu.Unlock()
+
+	fmt.Print(s)
+}
+
+func (m *FloodMeter) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.done)
+	})
+}
+
+// Run reports target's progress every floodProgressInterval until stopped.
+func (m *FloodMeter) Run(target *Target, report func(error)) {
+	ticker := time.NewTicker(floodProgressInterval)
+	defer ticker.Stop()
+
+	m.at = time.Now()
+
+	for {
+		var now time.Time
+
+		select {
+		case <-m.done:
+			return
+		case now = <-ticker.C:
+		}
+
+		stats := target.prober.Statistics()
+
+		seconds := now.Sub(m.at).Seconds()
+
+		sendRate := float64(stats.PacketsSent-m.sent) / seconds
+		replyRate := float64(stats.PacketsRecv-m.received) / seconds
+
+		m.sent, m.received, m.at = stats.PacketsSent, stats.PacketsRecv, now
+
+		report(showFloodProgress(stats, sendRate, replyRate, target, m.dots))
+	}
+}
+
+// floodRates returns the probes sent, and replies received, per second over target's whole run.
+func floodRates(stats *ping.Statistics, target *Target) (float64, float64) {
+	seconds := time.Since(target.startTime).Seconds()
+	if seconds <= 0 {
+		return 0, 0
+	}
+
+	return float64(stats.PacketsSent) / seconds, float64(stats.PacketsRecv) / seconds
+}
+
+// lossUnderLoad returns the share of probes declared lost so far, leaving out those still in flight,
+// of which there are many more at flood rates than at one probe a second.
+func lossUnderLoad(target *Target) float64 {
+	counts := target.tracker.Counts()
+	if counts.Sent == 0 {
+		return 0
+	}
+
+	return float64(counts.Lost) / float64(counts.Sent) * 100
+}
+
+// showFloodProgress reports target's progress, only to its log file if the indicator has the terminal.
+func showFloodProgress(stats *ping.Statistics, sendRate, replyRate float64, target *Target, dots bool) error {
+	colors := target.colors
+
+	switch format {
+	case "json":
+		return target.out.Event(newJSONProgress(stats, sendRate, replyRate, target))
+	case "csv":
+		return nil
+	}
+
+	if quiet {
+		return nil
+	}
+
+	printf := target.out.Printf
+	if dots {
+		printf = target.out.Logf
+	}
+
+	return printf("%ssent=%s received=%s loss=%s rate=%s/%s pps avg=%s\n",
+		linePrefix(target),
+		colors.Blue.Sprint(stats.PacketsSent),
+		colors.Blue.Sprint(stats.PacketsRecv),
+		highlightPacketLoss(lossUnderLoad(target), colors),
+		colors.Blue.Sprintf("%.0f", sendRate),
+		colors.Blue.Sprintf("%.0f", replyRate),
+		highlightLongRTT(stats.AvgRtt.Round(time.Microsecond), target.MaxRtt, colors, true))
+}
@@ -0,0 +1,301 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	ping "github.com/prometheus-community/pro-bing"
+)
+
+// The request an HTTPProber is currently waiting on
+type httpCall struct {
+	pkt     *ping.Packet
+	ignored bool
+	invalid string
+}
+
+// HTTPProber adapts pro-bing's HTTPCaller to the Prober interface, logging each
+// request with its DNS, connect, TLS and time-to-first-byte breakdown.
+//
+// Calls are made one at a time, so that a failure reported through the caller's
+// logger can be pinned on the request that caused it.
+type HTTPProber struct {
+	Count   int
+	Timeout time.Duration
+
+	OnSend   func(*ping.Packet)
+	OnRecv   func(*Reply)
+	OnFail   func(*ping.Packet, error)
+	OnFinish func(*ping.Statistics)
+
+	probeStats
+
+	caller *ping.HTTPCaller
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	current *httpCall
+	seq     int
+	settled int
+}
+
+// httpLogger receives the errors HTTPCaller has no callback for.
+type httpLogger struct {
+	ping.NoopLogger
+	prober *HTTPProber
+}
+
+func (l httpLogger) Errorf(format string, v ...any) {
+	for _, arg := range v {
+		err, ok := arg.(error)
+		if ok {
+			l.prober.failed(err)
+
+			return
+		}
+	}
+
+	l.prober.failed(fmt.Errorf(format, v...))
+}
+
+func validExpectedStatus(status int) bool {
+	return status >= 100 && status <= 599
+}
+
+// validStatus reports whether status counts as a successful probe; without --expect-status, any non-error status will do.
+func validStatus(status, expected int) bool {
+	if expected != 0 {
+		return status == expected
+	}
+
+	return status < http.StatusBadRequest
+}
+
+func newHTTPProber(target *Target) (*HTTPProber, error) {
+	// HTTPCaller sets its own pace, of one call per interval
+	if flood || preload > 0 || burst > 1 {
+		return nil, ErrPacingHTTP
+	}
+
+	ipaddr, err := target.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	prober := &HTTPProber{
+		Count:   probeCount(),
+		Timeout: timeout,
+		probeStats: probeStats{
+			addr:   target.Host,
+			ipaddr: ipaddr,
+		},
+	}
+
+	prober.ctx, prober.cancel = context.WithCancel(context.Background())
+
+	dialer := target.Binding.dialer("tcp")
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// Every request opens a fresh connection, so each one measures DNS, connect and TLS
+	transport.DisableKeepAlives = true
+
+	transport.TLSClientConfig = newTLSConfig("")
+
+	transport.DialContext = func(ctx context.Context, network, address string) (net.Conn, error) {
+		// Connect to the address --all-addresses pinned the target to, rather than whichever the URL's host resolves to
+		if target.Address != "" {
+			_, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+
+			address = net.JoinHostPort(target.Address, port)
+		}
+
+		return dialer.DialContext(ctx, strings.Replace(target.Network, "ip", "tcp", 1), address)
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		// Time the request we were asked to, rather than wherever it redirects to
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	prober.caller = ping.NewHTTPCaller(target.URL,
+		ping.WithHTTPCallerClient(client),
+		ping.WithHTTPCallerCallFrequency(target.Interval),
+		ping.WithHTTPCallerMaxConcurrentCalls(1),
+		ping.WithHTTPCallerTimeout(replyTimeout),
+		ping.WithHTTPCallerLogger(httpLogger{prober: prober}),
+		ping.WithHTTPCallerOnReq(prober.onReq),
+		ping.WithHTTPCallerOnConnDone(prober.onConnDone),
+		ping.WithHTTPCallerIsValidResponse(func(response *http.Response, body []byte) bool {
+			return prober.validate(response, body, target)
+		}),
+		ping.WithHTTPCallerOnResp(prober.onResp))
+
+	return prober, nil
+}
+
+func (p *HTTPProber) Stop() {
+	p.cancel()
+}
+
+func (p *HTTPProber) Run() error {
+	deadline := time.AfterFunc(p.Timeout, p.cancel)
+	defer deadline.Stop()
+
+	p.caller.RunWithContext(p.ctx)
+
+	if p.OnFinish != nil {
+		p.OnFinish(p.Statistics())
+	}
+
+	return nil
+}
+
+func (p *HTTPProber) onReq(suite *ping.TraceSuite) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	call := &httpCall{
+		pkt: &ping.Packet{
+			Addr:   p.addr,
+			IPAddr: p.IPAddr(),
+			Seq:    p.seq % int(sequenceSpace),
+		},
+	}
+
+	p.current = call
+
+	// The scheduler may squeeze in one more call before it notices we are done
+	if p.Count != 0 && p.seq >= p.Count {
+		call.ignored = true
+
+		return
+	}
+
+	p.seq++
+
+	p.updateSent()
+
+	if p.OnSend != nil {
+		p.OnSend(call.pkt)
+	}
+}
+
+func (p *HTTPProber) onConnDone(suite *ping.TraceSuite, network, address string, err error) {
+	if err != nil {
+		return
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.current.pkt.IPAddr = &net.IPAddr{IP: ip}
+	p.mu.Unlock()
+}
+
+func (p *HTTPProber) validate(response *http.Response, body []byte, target *Target) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case !validStatus(response.StatusCode, target.ExpectStatus):
+		p.current.invalid = fmt.Sprintf("unexpected status %d", response.StatusCode)
+	case target.ExpectBody != "" && !strings.Contains(string(body), target.ExpectBody):
+		p.current.invalid = fmt.Sprintf("response body does not contain %q", target.ExpectBody)
+	}
+
+	return p.current.invalid == ""
+}
+
+// settle takes the current call off the books, and reports whether it should be shown at all.
+func (p *HTTPProber) settle() (*httpCall, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	call := p.current
+	if call == nil || call.ignored {
+		return nil, false
+	}
+
+	p.current = nil
+
+	p.settled++
+	if p.Count != 0 && p.settled >= p.Count {
+		p.cancel()
+	}
+
+	return call, true
+}
+
+func (p *HTTPProber) onResp(suite *ping.TraceSuite, info *ping.HTTPCallInfo) {
+	call, ok := p.settle()
+	if !ok {
+		return
+	}
+
+	call.pkt.Rtt = suite.GetGeneralEnd().Sub(suite.GetGeneralStart())
+
+	if !info.IsValidResponse {
+		if p.OnFail != nil {
+			p.OnFail(call.pkt, errors.New(call.invalid))
+		}
+
+		return
+	}
+
+	p.updateStatistics(call.pkt)
+
+	reply := &Reply{
+		Packet: call.pkt,
+		Details: []Detail{
+			{Key: "status", Value: info.StatusCode},
+		},
+	}
+
+	reply.addSpan("dns", suite.GetDNSStart(), suite.GetDNSEnd())
+	reply.addSpan("connect", suite.GetConnStart(), suite.GetConnEnd())
+	reply.addSpan("tls", suite.GetTLSStart(), suite.GetTLSEnd())
+	reply.addSpan("ttfb", suite.GetGeneralStart(), suite.GetFirstByteReceived())
+
+	if p.OnRecv != nil {
+		p.OnRecv(reply)
+	}
+}
+
+func (p *HTTPProber) failed(err error) {
+	call, ok := p.settle()
+	if !ok || timedOut(err) {
+		// Requests that ran out of time are left for the tracker to declare lost
+		return
+	}
+
+	if p.OnFail != nil {
+		p.OnFail(call.pkt, err)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/net/icmp"
+)
+
+// The outcome of checking which ICMP socket type we may open, per address family
+type socketMode struct {
+	privileged bool
+	err        error
+}
+
+var socketModesMu sync.Mutex
+var socketModes = make(map[bool]socketMode)
+
+func canListen(network, address string) error {
+	conn, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return err
+	}
+
+	return conn.Close()
+}
+
+// icmpPrivileged reports whether ICMP pings over IPv4 (or IPv6) should use raw sockets,
+// as opposed to the unprivileged datagram sockets Linux and macOS offer to permitted groups.
+//
+// Unless --privileged or --unprivileged is given, raw sockets are preferred, and
+// datagram sockets are only used when raw sockets are refused.
+func icmpPrivileged(v6 bool) (bool, error) {
+	socketModesMu.Lock()
+	defer socketModesMu.Unlock()
+
+	mode, ok := socketModes[v6]
+	if ok {
+		return mode.privileged, mode.err
+	}
+
+	raw, datagram, address := "ip4:icmp", "udp4", "0.0.0.0"
+	if v6 {
+		raw, datagram, address = "ip6:ipv6-icmp", "udp6", "::"
+	}
+
+	switch {
+	case privileged:
+		mode.privileged = true
+
+		err := canListen(raw, address)
+		if errors.Is(err, os.ErrPermission) {
+			err = ErrNoRawSocket
+		}
+
+		mode.err = err
+	case unprivileged:
+		err := canListen(datagram, address)
+		if errors.Is(err, os.ErrPermission) {
+			err = ErrNoDatagramSocket
+		}
+
+		mode.err = err
+	default:
+		mode.privileged = true
+
+		err := canListen(raw, address)
+		if errors.Is(err, os.ErrPermission) {
+			mode.privileged = false
+
+			err = canListen(datagram, address)
+			if errors.Is(err, os.ErrPermission) {
+				err = fmt.Errorf("%w, and %w; --tcp <port> needs neither", ErrNoRawSocket, ErrNoDatagramSocket)
+			}
+		}
+
+		mode.err = err
+	}
+
+	socketModes[v6] = mode
+
+	return mode.privileged, mode.err
+}
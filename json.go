@@ -0,0 +1,353 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	ping "github.com/prometheus-community/pro-bing"
+)
+
+// Bump whenever a field is renamed, removed or changes meaning; adding fields does not require a bump
+const jsonSchemaVersion int = 2
+
+type jsonEvent struct {
+	Schema int    `json:"schema"`
+	Type   string `json:"type"`
+	Time   string `json:"time"`
+	Name   string `json:"name"`
+	Host   string `json:"host"`
+	Target string `json:"target"`
+}
+
+type jsonStart struct {
+	jsonEvent
+	Protocol       string `json:"protocol"`
+	Port           int    `json:"port,omitempty"`
+	URL            string `json:"url,omitempty"`
+	QueryName      string `json:"query_name,omitempty"`
+	QueryType      string `json:"query_type,omitempty"`
+	ServerName     string `json:"server_name,omitempty"`
+	Source         string `json:"source,omitempty"`
+	Interface      string `json:"interface,omitempty"`
+	TrafficClass   int    `json:"traffic_class,omitempty"`
+	Mark           int    `json:"mark,omitempty"`
+	Bytes          int    `json:"bytes,omitempty"`
+	TTL            int    `json:"ttl,omitempty"`
+	IntervalNs     int64  `json:"interval_ns"`
+	Burst          int    `json:"burst,omitempty"`
+	BurstSpacingNs int64  `json:"burst_spacing_ns,omitempty"`
+	ReplyTimeoutNs int64  `json:"reply_timeout_ns"`
+}
+
+type jsonReply struct {
+	jsonEvent
+	Seq       uint64         `json:"seq"`
+	Address   string         `json:"address"`
+	Port      int            `json:"port,omitempty"`
+	Bytes     int            `json:"bytes,omitempty"`
+	TTL       int            `json:"ttl,omitempty"`
+	RttNs     int64          `json:"rtt_ns"`
+	Details   map[string]any `json:"details,omitempty"`
+	Reordered bool           `json:"reordered,omitempty"`
+}
+
+type jsonLost struct {
+	jsonEvent
+	Seq    uint64 `json:"seq"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// A variant of a compared host that has stopped responding while others still are, or started responding again
+type jsonVariant struct {
+	jsonEvent
+	Seq     uint64 `json:"seq"`
+	Variant string `json:"variant"`
+}
+
+type jsonAddress struct {
+	jsonEvent
+	Previous string `json:"previous"`
+}
+
+type jsonSummary struct {
+	jsonEvent
+	Final       bool              `json:"final"`
+	Sent        int               `json:"sent"`
+	Received    int               `json:"received"`
+	Lost        int               `json:"lost"`
+	Reordered   int               `json:"reordered"`
+	Late        int               `json:"late"`
+	Duplicates  int               `json:"duplicates"`
+	LossPercent float64           `json:"loss_percent"`
+	MinRttNs    int64             `json:"min_rtt_ns"`
+	AvgRttNs    int64             `json:"avg_rtt_ns"`
+	MaxRttNs    int64             `json:"max_rtt_ns"`
+	StdDevRttNs int64             `json:"stddev_rtt_ns"`
+	ElapsedNs   int64             `json:"elapsed_ns"`
+	SendPps     float64           `json:"send_pps,omitempty"`
+	ReplyPps    float64           `json:"reply_pps,omitempty"`
+	Bursts      *jsonBurstSummary `json:"bursts,omitempty"`
+}
+
+// The spread across a --burst target's bursts, of their loss, median round-trip time and jitter
+type jsonBurstSummary struct {
+	Count       int     `json:"count"`
+	Lossy       int     `json:"lossy"`
+	WorstLoss   float64 `json:"worst_loss_percent"`
+	MedianP50Ns int64   `json:"median_p50_ns"`
+	MedianP95Ns int64   `json:"median_p95_ns"`
+	MedianMaxNs int64   `json:"median_max_ns"`
+	SpreadP50Ns int64   `json:"spread_p50_ns"`
+	SpreadP95Ns int64   `json:"spread_p95_ns"`
+	SpreadMaxNs int64   `json:"spread_max_ns"`
+}
+
+// A --burst target's burst, once every probe in it has been answered or lost
+type jsonBurst struct {
+	jsonEvent
+	Burst       uint64  `json:"burst"`
+	Sent        int     `json:"sent"`
+	Received    int     `json:"received"`
+	LossPercent float64 `json:"loss_percent"`
+	MinRttNs    int64   `json:"min_rtt_ns,omitempty"`
+	MedianRttNs int64   `json:"median_rtt_ns,omitempty"`
+	MaxRttNs    int64   `json:"max_rtt_ns,omitempty"`
+	SpreadNs    int64   `json:"spread_ns"`
+}
+
+// A --flood target's progress, with its rates over the last second
+type jsonProgress struct {
+	jsonEvent
+	Sent        int     `json:"sent"`
+	Received    int     `json:"received"`
+	LossPercent float64 `json:"loss_percent"`
+	SendPps     float64 `json:"send_pps"`
+	ReplyPps    float64 `json:"reply_pps"`
+	AvgRttNs    int64   `json:"avg_rtt_ns"`
+}
+
+func newJSONEvent(eventType string, target *Target) jsonEvent {
+	return jsonEvent{
+		Schema: jsonSchemaVersion,
+		Type:   eventType,
+		Time:   time.Now().Format(time.RFC3339Nano),
+		Name:   target.Name,
+		Host:   target.Host,
+		Target: target.address.Current().String(),
+	}
+}
+
+func newJSONStart(target *Target) jsonStart {
+	event := jsonStart{
+		jsonEvent:      newJSONEvent("start", target),
+		Protocol:       target.Protocol,
+		Port:           target.Port,
+		URL:            target.URL,
+		QueryName:      target.QueryName,
+		QueryType:      strings.ToUpper(target.QueryType),
+		ServerName:     target.ServerName,
+		Source:         target.Source,
+		Interface:      target.Interface,
+		TrafficClass:   target.TrafficClass,
+		Mark:           target.Mark,
+		IntervalNs:     target.Interval.Nanoseconds(),
+		ReplyTimeoutNs: replyTimeout.Nanoseconds(),
+	}
+
+	if target.Protocol == ProtocolICMP {
+		event.Bytes = target.Size
+		event.TTL = ttl
+	}
+
+	if burst > 1 {
+		event.Burst = burst
+		event.BurstSpacingNs = burstSpacing.Nanoseconds()
+	}
+
+	return event
+}
+
+func newJSONReply(eventType string, reply *Reply, seq uint64, target *Target) jsonReply {
+	event := jsonReply{
+		jsonEvent: newJSONEvent(eventType, target),
+		Seq:       seq,
+		Address:   reply.IPAddr.String(),
+		Port:      target.Port,
+		RttNs:     reply.Rtt.Nanoseconds(),
+	}
+
+	if target.Protocol == ProtocolICMP {
+		event.Bytes = reply.Nbytes - 8
+		event.TTL = reply.TTL
+	}
+
+	if len(reply.Details) > 0 {
+		event.Details = make(map[string]any, len(reply.Details))
+
+		for _, detail := range reply.Details {
+			duration, ok := detail.Value.(time.Duration)
+			if ok {
+				event.Details[detail.Key+"_ns"] = duration.Nanoseconds()
+			} else {
+				event.Details[detail.Key] = detail.Value
+			}
+		}
+	}
+
+	return event
+}
+
+func newJSONLost(seq uint64, reason string, target *Target) jsonLost {
+	return jsonLost{
+		jsonEvent: newJSONEvent("lost", target),
+		Seq:       seq,
+		Reason:    reason,
+	}
+}
+
+func newJSONVariant(stopped bool, seq uint64, target *Target) jsonVariant {
+	eventType := "variant_up"
+	if stopped {
+		eventType = "variant_down"
+	}
+
+	return jsonVariant{
+		jsonEvent: newJSONEvent(eventType, target),
+		Seq:       seq,
+		Variant:   target.Variant,
+	}
+}
+
+func newJSONAddress(previous, ipaddr *net.IPAddr, target *Target) jsonAddress {
+	event := jsonAddress{
+		jsonEvent: newJSONEvent("address", target),
+		Previous:  previous.String(),
+	}
+
+	// The prober may not have moved yet, but the event is about where it is going
+	event.Target = ipaddr.String()
+
+	return event
+}
+
+// Sweeps have no single target, so events name the range swept as the host, and the address as the target
+type jsonSweepHost struct {
+	jsonEvent
+	TTL   int   `json:"ttl,omitempty"`
+	RttNs int64 `json:"rtt_ns,omitempty"`
+}
+
+type jsonSweepSummary struct {
+	jsonEvent
+	Up        int   `json:"up"`
+	Addresses int   `json:"addresses"`
+	ElapsedNs int64 `json:"elapsed_ns"`
+}
+
+func newJSONSweepEvent(eventType, target, sweep string) jsonEvent {
+	return jsonEvent{
+		Schema: jsonSchemaVersion,
+		Type:   eventType,
+		Time:   time.Now().Format(time.RFC3339Nano),
+		Name:   sweep,
+		Host:   sweep,
+		Target: target,
+	}
+}
+
+func newJSONSweepHost(eventType string, addr netip.Addr, reply *sweepReply, sweep string) jsonSweepHost {
+	event := jsonSweepHost{
+		jsonEvent: newJSONSweepEvent(eventType, addr.String(), sweep),
+	}
+
+	if reply != nil {
+		event.TTL = reply.ttl
+		event.RttNs = reply.rtt.Nanoseconds()
+	}
+
+	return event
+}
+
+func newJSONSweepSummary(up, addresses int, elapsed time.Duration, sweep string) jsonSweepSummary {
+	return jsonSweepSummary{
+		jsonEvent: newJSONSweepEvent("sweep", "", sweep),
+		Up:        up,
+		Addresses: addresses,
+		ElapsedNs: elapsed.Nanoseconds(),
+	}
+}
+
+func newJSONSummary(stats *ping.Statistics, target *Target, isEnding bool) jsonSummary {
+	counts := target.tracker.Counts()
+
+	event := jsonSummary{
+		jsonEvent:   newJSONEvent("summary", target),
+		Final:       isEnding,
+		Sent:        stats.PacketsSent,
+		Received:    stats.PacketsRecv,
+		Lost:        counts.Lost,
+		Reordered:   counts.Reordered,
+		Late:        counts.Late,
+		Duplicates:  stats.PacketsRecvDuplicates,
+		LossPercent: stats.PacketLoss,
+		MinRttNs:    stats.MinRtt.Nanoseconds(),
+		AvgRttNs:    stats.AvgRtt.Nanoseconds(),
+		MaxRttNs:    stats.MaxRtt.Nanoseconds(),
+		StdDevRttNs: stats.StdDevRtt.Nanoseconds(),
+		ElapsedNs:   time.Since(target.startTime).Nanoseconds(),
+	}
+
+	if flood {
+		event.SendPps, event.ReplyPps = floodRates(stats, target)
+	}
+
+	if target.bursts != nil {
+		summary := target.bursts.Summary()
+
+		event.Bursts = &jsonBurstSummary{
+			Count:       summary.Bursts,
+			Lossy:       summary.Lossy,
+			WorstLoss:   summary.WorstLoss,
+			MedianP50Ns: summary.MedianP50.Nanoseconds(),
+			MedianP95Ns: summary.MedianP95.Nanoseconds(),
+			MedianMaxNs: summary.MedianMax.Nanoseconds(),
+			SpreadP50Ns: summary.SpreadP50.Nanoseconds(),
+			SpreadP95Ns: summary.SpreadP95.Nanoseconds(),
+			SpreadMaxNs: summary.SpreadMax.Nanoseconds(),
+		}
+	}
+
+	return event
+}
+
+func newJSONProgress(stats *ping.Statistics, sendRate, replyRate float64, target *Target) jsonProgress {
+	return jsonProgress{
+		jsonEvent:   newJSONEvent("progress", target),
+		Sent:        stats.PacketsSent,
+		Received:    stats.PacketsRecv,
+		LossPercent: lossUnderLoad(target),
+		SendPps:     sendRate,
+		ReplyPps:    replyRate,
+		AvgRttNs:    stats.AvgRtt.Nanoseconds(),
+	}
+}
+
+func newJSONBurst(result *BurstResult, target *Target) jsonBurst {
+	return jsonBurst{
+		jsonEvent:   newJSONEvent("burst", target),
+		Burst:       result.Burst,
+		Sent:        result.Sent,
+		Received:    result.Received,
+		LossPercent: result.Loss,
+		MinRttNs:    result.Min.Nanoseconds(),
+		MedianRttNs: result.Median.Nanoseconds(),
+		MaxRttNs:    result.Max.Nanoseconds(),
+		SpreadNs:    result.Spread.Nanoseconds(),
+	}
+}
@@ -7,21 +7,96 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
-	"time"
 )
 
-func parseTime(line string) (string, error) {
-	fields := strings.Fields(line)
+// Matches both current "Packet N lost." lines and the older "lost or arrived out of order" wording
+var lostPacket = regexp.MustCompile(`Packet (\d+) lost`)
 
-	t, err := time.Parse(DATE, strings.Join(fields[:3], " "))
-	if err != nil {
-		return "", err
+// Matches replies that turned up after their packet had already been logged as lost, for any protocol
+var latePacket = regexp.MustCompile(`[a-z]+_seq=(\d+) .*\(LATE!\)`)
+
+type lossPeriod struct {
+	start string
+	end   string
+	lost  map[uint64]struct{}
+}
+
+// lossHistory holds the loss periods for one target in a log, since logs shared
+// by several targets interleave their lines.
+type lossHistory struct {
+	periods       []*lossPeriod
+	current       *lossPeriod
+	lastTimestamp string
+
+	// Remembers which period each lost packet was logged in, so a late reply can be taken back out
+	lostIn map[uint64]*lossPeriod
+}
+
+// splitLine separates a log line into its timestamp, its target label (if any) and the message.
+func splitLine(line string, timestamped bool) (string, string, string) {
+	if !timestamped {
+		label, message, _ := strings.Cut(line, " | ")
+
+		return "", label, message
 	}
 
-	return t.Format(DATE), nil
+	fields := strings.SplitN(line, " | ", 3)
+
+	switch len(fields) {
+	case 3:
+		return strings.TrimSpace(fields[0]), fields[1], fields[2]
+	case 2:
+		return strings.TrimSpace(fields[0]), "", fields[1]
+	default:
+		return "", "", line
+	}
+}
+
+func (h *lossHistory) record(timestamp, message string) {
+	late := latePacket.FindStringSubmatch(message)
+	if late != nil {
+		seq, _ := strconv.ParseUint(late[1], 10, 64)
+
+		period, ok := h.lostIn[seq]
+		if ok {
+			delete(period.lost, seq)
+			delete(h.lostIn, seq)
+		}
+	}
+
+	lost := lostPacket.FindStringSubmatch(message)
+
+	switch {
+	case lost != nil && h.current == nil:
+		h.current = &lossPeriod{
+			start: h.lastTimestamp,
+			end:   h.lastTimestamp,
+			lost:  make(map[uint64]struct{}),
+		}
+		if h.current.start == "" {
+			h.current.start = timestamp
+		}
+
+		h.periods = append(h.periods, h.current)
+
+		fallthrough
+	case lost != nil:
+		seq, _ := strconv.ParseUint(lost[1], 10, 64)
+
+		h.current.lost[seq] = struct{}{}
+		h.lostIn[seq] = h.current
+	case h.current != nil:
+		h.current.end = timestamp
+		h.current = nil
+	}
+
+	h.lastTimestamp = timestamp
 }
 
 func calculateLoss(logFile string) error {
@@ -36,11 +111,12 @@ func calculateLoss(logFile string) error {
 		return err
 	}
 
-	var lostLastPacket bool
-	var lastTimestamp string
-	var lostPacketCount int
-	var startTime string
-	var endTime string
+	histories := make(map[string]*lossHistory)
+	var labels []string
+
+	// Unless told otherwise, work out the timestamp format from the first line that has one
+	format := lossTimestampFormat
+	timestamped := true
 
 	regex := regexp.MustCompile(escapeSequences)
 
@@ -52,36 +128,80 @@ func calculateLoss(logFile string) error {
 			continue
 		}
 
-		timestamp, err := parseTime(stripped)
-		if err != nil {
-			return err
+		timestamp, label, message := splitLine(stripped, timestamped)
+
+		if format == "" && timestamped {
+			format, err = detectTimestampFormat(timestamp)
+
+			switch {
+			// Logs of several targets written with --timestamp=false start with each line's label instead
+			case err != nil && label == "":
+				timestamped = false
+
+				timestamp, label, message = splitLine(stripped, timestamped)
+			case err != nil:
+				return err
+			}
 		}
 
-		lostThisPacket := strings.Contains(stripped, "lost or arrived out of order")
-
-		switch {
-		case lostThisPacket && !lostLastPacket:
-			startTime = lastTimestamp
-			endTime = startTime
-			lostPacketCount = 1
-			lostLastPacket = true
-		case lostThisPacket && lostLastPacket:
-			lostPacketCount++
-			lostLastPacket = true
-		case !lostThisPacket && lostLastPacket:
-			endTime = timestamp
-			fmt.Printf("%s => %s [%d packet(s) lost]\n", startTime, endTime, lostPacketCount)
-			lostLastPacket = false
+		if timestamped {
+			err = parseTimestamp(timestamp, format)
+			if err != nil {
+				return err
+			}
 		}
 
-		lastTimestamp = timestamp
+		history, ok := histories[label]
+		if !ok {
+			history = &lossHistory{lostIn: make(map[uint64]*lossPeriod)}
+			histories[label] = history
+			labels = append(labels, label)
+		}
+
+		history.record(timestamp, message)
 	}
 
 	if err := scanner.Err(); err != nil {
 		return err
 	}
 
-	if lostPacketCount == 0 {
+	var found bool
+
+	for _, label := range labels {
+		history := histories[label]
+
+		// The log ended mid-outage, so close out the final period at the last recorded loss
+		if history.current != nil {
+			history.current.end = history.lastTimestamp
+		}
+
+		var prefix string
+
+		if label != "" {
+			prefix = label + ": "
+		}
+
+		for _, period := range history.periods {
+			if len(period.lost) == 0 {
+				continue
+			}
+
+			found = true
+
+			// Without timestamps, the packets lost are the only way to tell periods apart
+			if !timestamped {
+				seqs := slices.Sorted(maps.Keys(period.lost))
+
+				fmt.Printf("%sPacket %d => %d [%d packet(s) lost]\n", prefix, seqs[0], seqs[len(seqs)-1], len(period.lost))
+
+				continue
+			}
+
+			fmt.Printf("%s%s => %s [%d packet(s) lost]\n", prefix, period.start, period.end, len(period.lost))
+		}
+	}
+
+	if !found {
 		fmt.Println("No dropped packets found")
 	} else {
 		fmt.Println()
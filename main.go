@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"log"
 	"math"
+	"runtime"
 	"strings"
 	"time"
 
@@ -22,42 +23,164 @@ const (
 )
 
 var (
-	ErrInvalidCount = errors.New("count must be a positive integer")
-	ErrInvalidSize  = errors.New("size must be a positive integer between 1 and 65527 bytes inclusive")
-	ErrInvalidTtl   = errors.New("ttl must be a positive integer no higher than 255")
+	ErrBurstTooLong             = errors.New("each burst must finish within the interval, so --burst-spacing times one less than --burst must be shorter than --interval")
+	ErrInvalidBurst             = errors.New("burst must be an integer between 1 and 65536")
+	ErrInvalidBurstSpacing      = errors.New("burst spacing must not be negative")
+	ErrInvalidConcurrency       = errors.New("concurrency must be a positive integer")
+	ErrInvalidCount             = errors.New("count must be a positive integer")
+	ErrInvalidDSCP              = errors.New("dscp must be a code point name (e.g. EF, AF41, BE) or a number from 0 to 63")
+	ErrInvalidDNSType           = errors.New("dns query type must be one of: A, AAAA, ANY, CNAME, MX, NS, PTR, SOA, SRV, TXT")
+	ErrInvalidFormat            = errors.New("format must be one of: text, json, csv")
+	ErrInvalidInterval          = errors.New("interval must be a positive duration")
+	ErrPacingHTTP               = errors.New("--flood, --preload and --burst are not supported for http targets")
+	ErrPacingUnprivileged       = errors.New("--flood, --preload and --burst send icmp pings over raw sockets, which need root or the cap_net_raw capability, and rule out --unprivileged")
+	ErrInterfaceUnsupported     = errors.New("binding to an interface by name is only supported on Linux; pass one of its addresses instead")
+	ErrInvalidMark              = errors.New("mark must not be negative")
+	ErrInvalidMaxHops           = errors.New("max hops must be a positive integer no higher than 255")
+	ErrNoTargets                = errors.New("at least one host or a targets file must be given")
+	ErrMarkUnsupported          = errors.New("marking probes is only supported on Linux")
+	ErrPMTUUnsupported          = errors.New("pmtu sets the don't fragment bit on its probes, which is only supported on Linux")
+	ErrNoDatagramSocket         = errors.New("unprivileged ICMP sockets are not permitted for this user's group (see the net.ipv4.ping_group_range sysctl)")
+	ErrNoRawSocket              = errors.New("raw ICMP sockets require root or the cap_net_raw capability (e.g. setcap cap_net_raw=+ep /path/to/pinglog)")
+	ErrInvalidRange             = errors.New("range must be an address or a CIDR prefix, e.g. 192.0.2.0/24")
+	ErrInvalidReorder           = errors.New("reorder window must not be negative")
+	ErrInvalidResolveEvery      = errors.New("resolve interval must not be negative")
+	ErrInvalidReplyTimeout      = errors.New("reply timeout must be a positive duration")
+	ErrSocketOptionsUnsupported = errors.New("setting the interface, traffic class or mark of tcp, tls, dns and http probes is only supported on Linux")
+	ErrOverwriteDeclined        = errors.New("log file already exists; use --force to overwrite or --append to add to it")
+	ErrInvalidPort              = errors.New("port must be an integer between 1 and 65535")
+	ErrInvalidPreload           = errors.New("preload must be an integer between 0 and 65536")
+	ErrInvalidStatus            = errors.New("expected status must be an HTTP status code between 100 and 599")
+	ErrInvalidSize              = errors.New("size must be a positive integer between 1 and 65527 bytes inclusive")
+	ErrInvalidSource            = errors.New("source must be an IP address, given by either --source or --interface but not both")
+	ErrInvalidTos               = errors.New("tos must be an integer between 0 and 255")
+	ErrInvalidTimestamp         = errors.New("timestamp format must be one of: default, rfc3339, unix, elapsed, or a Go time layout")
+	ErrInvalidVia               = errors.New("via must be a comma-separated list of interfaces or source addresses")
+	ErrInvalidTtl               = errors.New("ttl must be a positive integer no higher than 255")
+	ErrRangeTooLarge            = errors.New("range must not contain more than 65536 addresses")
+	ErrTraceUnprivileged        = errors.New("trace needs raw ICMP sockets to receive time exceeded messages, which --unprivileged rules out")
 )
 
+var allAddresses bool
+var appendLog bool
 var beep bool
+var burst int
+var burstSpacing time.Duration
+var certWarnDays int
 var colorize bool
+var concurrency int
 var count int
+var dnsName string
+var dnsResolvers []string
+var dnsType string
 var dropped bool
+var dscp []string
+var dualStack bool
+var expectBody string
+var expectStatus int
+var force bool
+var flood bool
+var format string
+var iface string
 var interval time.Duration
+var insecure bool
 var ipv4 bool
 var ipv6 bool
+var logColor bool
+var mark int
+var lossTimestampFormat string
+var maxHops int
 var maxRtt time.Duration
+var output string
+var pmtuInterval time.Duration
+var preload int
+var privileged bool
 var quiet bool
+var reload bool
+var reorderWindow time.Duration
+var replyTimeout time.Duration
+var resolveEvery time.Duration
 var size int
+var sni string
+var source string
+var sweepInterval time.Duration
+var sweepReplyTimeout time.Duration
 var timeout time.Duration
 var timestamp bool
+var targetsFile string
+var tcpPort int
+var timestampFormat string
+var tos int
 var ttl int
+var unprivileged bool
+var utc bool
 var version bool
+var waitDNS bool
+var via []string
+var watch bool
 
 func main() {
 	cmd := &cobra.Command{
-		Use:   "pinglog [flags] <host>",
+		Use:   "pinglog [flags] <host> [host]...",
 		Short: "A more featureful ping tool.",
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.ArbitraryArgs,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
 			initializeConfig(cmd)
 		},
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if flood && !cmd.Flags().Changed("interval") {
+				interval = floodInterval
+			}
+
 			switch {
+			case len(args) == 0 && targetsFile == "" && len(dnsResolvers) == 0:
+				return ErrNoTargets
+			case burst < 1 || uint64(burst) > sequenceSpace:
+				return ErrInvalidBurst
+			case burstSpacing < 0:
+				return ErrInvalidBurstSpacing
+			case interval <= 0:
+				return ErrInvalidInterval
+			case burst > 1 && time.Duration(burst-1)*burstSpacing >= interval:
+				return ErrBurstTooLong
 			case count < 0:
 				return ErrInvalidCount
+			case format != "text" && format != "json" && format != "csv":
+				return ErrInvalidFormat
+			case preload < 0 || uint64(preload) > sequenceSpace:
+				return ErrInvalidPreload
+			case replyTimeout <= 0:
+				return ErrInvalidReplyTimeout
+			case reorderWindow < 0:
+				return ErrInvalidReorder
+			case resolveEvery < 0:
+				return ErrInvalidResolveEvery
 			case size < 1 || size > 65527:
 				return ErrInvalidSize
+			case tcpPort < 0 || tcpPort > 65535:
+				return ErrInvalidPort
+			case !validDNSType(dnsType):
+				return ErrInvalidDNSType
+			case expectStatus != 0 && !validExpectedStatus(expectStatus):
+				return ErrInvalidStatus
 			case ttl < 1 || ttl > 255:
 				return ErrInvalidTtl
+			case !validSource():
+				return ErrInvalidSource
+			case !validVia():
+				return ErrInvalidVia
+			case (interfaceName() != "" || viaInterfaces()) && runtime.GOOS != "linux":
+				return ErrInterfaceUnsupported
+			case !validDSCP():
+				return ErrInvalidDSCP
+			case tos < 0 || tos > 255:
+				return ErrInvalidTos
+			case mark < 0:
+				return ErrInvalidMark
+			case mark != 0 && runtime.GOOS != "linux":
+				return ErrMarkUnsupported
+			case !validTimestampFormat(timestampFormat):
+				return ErrInvalidTimestamp
 			}
 
 			return nil
@@ -76,6 +199,13 @@ func main() {
 		Use:   "loss <file1> [file2]...",
 		Short: "Calculate periods of packet loss from log file(s)",
 		Args:  cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if lossTimestampFormat != "" && !validTimestampFormat(lossTimestampFormat) {
+				return ErrInvalidTimestamp
+			}
+
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			for file := range args {
 				err := calculateLoss(args[file])
@@ -88,6 +218,8 @@ func main() {
 		},
 	}
 
+	lossCmd.Flags().StringVar(&lossTimestampFormat, "timestamp-format", "", "timestamp format used by the log file (detected automatically if unset)")
+
 	cmd.AddCommand(lossCmd)
 
 	var stripCmd = &cobra.Command{
@@ -106,21 +238,245 @@ func main() {
 
 	cmd.AddCommand(stripCmd)
 
+	traceCmd := &cobra.Command{
+		Use:   "trace [flags] <host>",
+		Short: "Discover the path to a host and monitor loss and latency at every hop",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case count < 0:
+				return ErrInvalidCount
+			case maxHops < 1 || maxHops > 255:
+				return ErrInvalidMaxHops
+			case replyTimeout <= 0:
+				return ErrInvalidReplyTimeout
+			case !validSource():
+				return ErrInvalidSource
+			case interfaceName() != "" && runtime.GOOS != "linux":
+				return ErrInterfaceUnsupported
+			case !validTimestampFormat(timestampFormat):
+				return ErrInvalidTimestamp
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runTrace(args[0])
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	traceCmd.Flags().BoolVarP(&appendLog, "append", "a", false, "append to log file instead of overwriting it")
+	traceCmd.Flags().BoolVarP(&colorize, "color", "C", true, "enable colorized output")
+	traceCmd.Flags().IntVarP(&count, "count", "c", 0, "number of rounds of probes to send")
+	traceCmd.Flags().BoolVarP(&dropped, "dropped", "d", true, "log dropped probes")
+	traceCmd.Flags().BoolVarP(&force, "force", "f", false, "overwrite log file without prompting")
+	traceCmd.MarkFlagsMutuallyExclusive("append", "force")
+	traceCmd.Flags().StringVarP(&iface, "interface", "I", "", "send probes through this interface, or from this address")
+	traceCmd.Flags().DurationVarP(&interval, "interval", "i", time.Second, "time between rounds of probes")
+	traceCmd.Flags().BoolVarP(&ipv4, "ipv4", "4", false, "force dns resolution to ipv4")
+	traceCmd.Flags().BoolVarP(&ipv6, "ipv6", "6", false, "force dns resolution to ipv6")
+	traceCmd.MarkFlagsMutuallyExclusive("ipv4", "ipv6")
+	traceCmd.Flags().BoolVar(&logColor, "log-color", false, "keep ANSI color codes in log file")
+	traceCmd.Flags().IntVar(&maxHops, "max-hops", 30, "maximum number of hops to probe")
+	traceCmd.Flags().StringVarP(&output, "output", "o", "", "write to the specified file as well as stdout")
+	traceCmd.Flags().Lookup("output").NoOptDefVal = defaultLogFile
+	traceCmd.Flags().DurationVarP(&replyTimeout, "reply-timeout", "W", 2*time.Second, "time to wait for a reply before declaring a probe lost")
+	traceCmd.Flags().StringVar(&source, "source", "", "send probes from this address")
+	traceCmd.Flags().DurationVarP(&timeout, "timeout", "w", time.Duration(math.MaxInt64), "timeout before trace exits, regardless of number of probes sent")
+	traceCmd.Flags().BoolVarP(&timestamp, "timestamp", "t", true, "prepend timestamps to output")
+	traceCmd.Flags().StringVar(&timestampFormat, "timestamp-format", TimestampDefault, "timestamp format (default, rfc3339, unix, elapsed, or a Go time layout)")
+	traceCmd.Flags().BoolVar(&utc, "utc", false, "display timestamps in UTC instead of local time")
+
+	cmd.AddCommand(traceCmd)
+
+	pmtuCmd := &cobra.Command{
+		Use:   "pmtu [flags] <host>",
+		Short: "Discover the path MTU to a host over IPv4 and IPv6",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case runtime.GOOS != "linux":
+				return ErrPMTUUnsupported
+			case count < 0:
+				return ErrInvalidCount
+			case replyTimeout <= 0:
+				return ErrInvalidReplyTimeout
+			case !validSource():
+				return ErrInvalidSource
+			case !validTimestampFormat(timestampFormat):
+				return ErrInvalidTimestamp
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runPMTU(args[0])
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	pmtuCmd.Flags().BoolVarP(&appendLog, "append", "a", false, "append to log file instead of overwriting it")
+	pmtuCmd.Flags().BoolVarP(&colorize, "color", "C", true, "enable colorized output")
+	pmtuCmd.Flags().IntVarP(&count, "count", "c", 0, "number of checks to make with --watch")
+	pmtuCmd.Flags().BoolVarP(&force, "force", "f", false, "overwrite log file without prompting")
+	pmtuCmd.MarkFlagsMutuallyExclusive("append", "force")
+	pmtuCmd.Flags().StringVarP(&iface, "interface", "I", "", "send probes through this interface, or from this address")
+	pmtuCmd.Flags().DurationVarP(&pmtuInterval, "interval", "i", 10*time.Second, "time between checks with --watch")
+	pmtuCmd.Flags().BoolVarP(&ipv4, "ipv4", "4", false, "only discover the path mtu over ipv4")
+	pmtuCmd.Flags().BoolVarP(&ipv6, "ipv6", "6", false, "only discover the path mtu over ipv6")
+	pmtuCmd.MarkFlagsMutuallyExclusive("ipv4", "ipv6")
+	pmtuCmd.Flags().BoolVar(&logColor, "log-color", false, "keep ANSI color codes in log file")
+	pmtuCmd.Flags().StringVarP(&output, "output", "o", "", "write to the specified file as well as stdout")
+	pmtuCmd.Flags().Lookup("output").NoOptDefVal = defaultLogFile
+	pmtuCmd.Flags().BoolVar(&privileged, "privileged", false, "only send icmp pings over raw sockets")
+	pmtuCmd.Flags().BoolVar(&unprivileged, "unprivileged", false, "only send icmp pings over unprivileged datagram sockets")
+	pmtuCmd.MarkFlagsMutuallyExclusive("privileged", "unprivileged")
+	pmtuCmd.Flags().DurationVarP(&replyTimeout, "reply-timeout", "W", 2*time.Second, "time to wait for a reply before deciding a size does not fit")
+	pmtuCmd.Flags().StringVar(&source, "source", "", "send probes from this address")
+	pmtuCmd.Flags().DurationVarP(&timeout, "timeout", "w", time.Duration(math.MaxInt64), "timeout before --watch exits, regardless of number of checks made")
+	pmtuCmd.Flags().BoolVarP(&timestamp, "timestamp", "t", true, "prepend timestamps to output")
+	pmtuCmd.Flags().StringVar(&timestampFormat, "timestamp-format", TimestampDefault, "timestamp format (default, rfc3339, unix, elapsed, or a Go time layout)")
+	pmtuCmd.Flags().BoolVar(&utc, "utc", false, "display timestamps in UTC instead of local time")
+	pmtuCmd.Flags().BoolVar(&watch, "watch", false, "keep checking the path mtu, and warn when it changes")
+
+	cmd.AddCommand(pmtuCmd)
+
+	sweepCmd := &cobra.Command{
+		Use:   "sweep [flags] <range>",
+		Short: "Find the hosts that answer pings in an address range",
+		Args:  cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			switch {
+			case concurrency < 1:
+				return ErrInvalidConcurrency
+			case count < 0:
+				return ErrInvalidCount
+			case format != "text" && format != "json" && format != "csv":
+				return ErrInvalidFormat
+			case sweepReplyTimeout <= 0:
+				return ErrInvalidReplyTimeout
+			case size < 1 || size > 65527:
+				return ErrInvalidSize
+			case ttl < 1 || ttl > 255:
+				return ErrInvalidTtl
+			case !validSource():
+				return ErrInvalidSource
+			case interfaceName() != "" && runtime.GOOS != "linux":
+				return ErrInterfaceUnsupported
+			case !validTimestampFormat(timestampFormat):
+				return ErrInvalidTimestamp
+			}
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			err := runSweep(args[0])
+			if err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	sweepCmd.Flags().BoolVarP(&appendLog, "append", "a", false, "append to log file instead of overwriting it")
+	sweepCmd.Flags().BoolVarP(&colorize, "color", "C", true, "enable colorized output")
+	sweepCmd.Flags().IntVar(&concurrency, "concurrency", 256, "number of addresses to ping at once")
+	sweepCmd.Flags().IntVarP(&count, "count", "c", 0, "number of sweeps to make with --watch")
+	sweepCmd.Flags().BoolVarP(&force, "force", "f", false, "overwrite log file without prompting")
+	sweepCmd.MarkFlagsMutuallyExclusive("append", "force")
+	sweepCmd.Flags().StringVar(&format, "format", "text", "output format (text, json, csv)")
+	sweepCmd.Flags().StringVarP(&iface, "interface", "I", "", "send probes through this interface, or from this address")
+	sweepCmd.Flags().DurationVarP(&sweepInterval, "interval", "i", time.Minute, "time between the start of each sweep with --watch")
+	sweepCmd.Flags().BoolVar(&logColor, "log-color", false, "keep ANSI color codes in log file")
+	sweepCmd.Flags().StringVarP(&output, "output", "o", "", "write to the specified file as well as stdout")
+	sweepCmd.Flags().Lookup("output").NoOptDefVal = defaultLogFile
+	sweepCmd.Flags().BoolVar(&privileged, "privileged", false, "only send icmp pings over raw sockets")
+	sweepCmd.Flags().BoolVar(&unprivileged, "unprivileged", false, "only send icmp pings over unprivileged datagram sockets")
+	sweepCmd.MarkFlagsMutuallyExclusive("privileged", "unprivileged")
+	sweepCmd.Flags().DurationVarP(&sweepReplyTimeout, "reply-timeout", "W", time.Second, "time to wait for a reply before deciding an address is down")
+	sweepCmd.Flags().IntVarP(&size, "size", "s", 56, "size of payload, in bytes")
+	sweepCmd.Flags().StringVar(&source, "source", "", "send probes from this address")
+	sweepCmd.Flags().DurationVarP(&timeout, "timeout", "w", time.Duration(math.MaxInt64), "timeout before --watch exits, regardless of number of sweeps made")
+	sweepCmd.Flags().BoolVarP(&timestamp, "timestamp", "t", true, "prepend timestamps to output")
+	sweepCmd.Flags().StringVar(&timestampFormat, "timestamp-format", TimestampDefault, "timestamp format (default, rfc3339, unix, elapsed, or a Go time layout)")
+	sweepCmd.Flags().IntVarP(&ttl, "ttl", "T", 128, "maximum time-to-live")
+	sweepCmd.Flags().BoolVar(&utc, "utc", false, "display timestamps in UTC instead of local time")
+	sweepCmd.Flags().BoolVar(&watch, "watch", false, "keep sweeping, and log hosts as they appear and disappear")
+
+	cmd.AddCommand(sweepCmd)
+
+	cmd.Flags().BoolVar(&allAddresses, "all-addresses", false, "probe every address each target resolves to, side by side")
+	cmd.Flags().BoolVarP(&appendLog, "append", "a", false, "append to log file instead of overwriting it")
 	cmd.Flags().BoolVarP(&beep, "beep", "b", true, "enable audible bell for exceeded max-rtt")
+	cmd.Flags().IntVar(&burst, "burst", 1, "send this many probes back to back every interval, and report on each burst as a whole")
+	cmd.Flags().DurationVar(&burstSpacing, "burst-spacing", time.Millisecond, "time between the probes of a burst")
+	cmd.Flags().IntVar(&certWarnDays, "cert-warn-days", 14, "highlight tls certificates expiring in fewer than this many days")
 	cmd.Flags().BoolVarP(&colorize, "color", "C", true, "enable colorized output")
-	cmd.Flags().IntVarP(&count, "count", "c", 0, "number of pings to send")
+	cmd.Flags().IntVarP(&count, "count", "c", 0, "number of pings (or with --burst, bursts) to send")
+	cmd.Flags().StringArrayVar(&dnsResolvers, "dns", nil, "time queries to this dns resolver (may be repeated)")
+	cmd.Flags().StringVar(&dnsName, "dns-name", "example.com", "name to query with --dns")
+	cmd.Flags().StringVar(&dnsType, "dns-type", "A", "record type to query with --dns")
 	cmd.Flags().BoolVarP(&dropped, "dropped", "d", true, "log dropped pings")
+	cmd.Flags().BoolVar(&dualStack, "dual-stack", false, "probe each target over both ipv4 and ipv6, side by side")
+	cmd.Flags().StringSliceVar(&dscp, "dscp", nil, "mark probes with this dscp class (e.g. EF, AF41, BE), or compare several side by side")
+	cmd.Flags().StringVar(&expectBody, "expect-body", "", "count http responses whose body lacks this text as lost")
+	cmd.Flags().IntVar(&expectStatus, "expect-status", 0, "count http responses with any other status as lost (default any status below 400)")
+	cmd.Flags().BoolVar(&flood, "flood", false, "send each probe as soon as the last is answered, or after --interval (default 10ms) if not")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "overwrite log file without prompting")
+	cmd.MarkFlagsMutuallyExclusive("append", "force")
+	cmd.MarkFlagsMutuallyExclusive("burst", "flood")
+	cmd.Flags().StringVar(&format, "format", "text", "output format (text, json, csv)")
+	cmd.Flags().BoolVar(&insecure, "insecure", false, "skip tls certificate verification for https and tls targets")
+	cmd.Flags().StringVarP(&iface, "interface", "I", "", "send probes through this interface, or from this address")
 	cmd.Flags().DurationVarP(&interval, "interval", "i", time.Second, "time between pings")
 	cmd.Flags().BoolVarP(&ipv4, "ipv4", "4", false, "force dns resolution to ipv4")
 	cmd.Flags().BoolVarP(&ipv6, "ipv6", "6", false, "force dns resolution to ipv6")
 	cmd.MarkFlagsMutuallyExclusive("ipv4", "ipv6")
+	cmd.MarkFlagsMutuallyExclusive("dual-stack", "ipv4")
+	cmd.MarkFlagsMutuallyExclusive("dual-stack", "ipv6")
+	cmd.MarkFlagsMutuallyExclusive("all-addresses", "dual-stack")
+	cmd.Flags().BoolVar(&logColor, "log-color", false, "keep ANSI color codes in log file")
+	cmd.Flags().IntVar(&mark, "mark", 0, "tag probes with this firewall mark, for policy routing")
 	cmd.Flags().DurationVarP(&maxRtt, "max-rtt", "m", time.Hour, "colorize pings over this rtt")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "write to the specified file as well as stdout")
+	cmd.Flags().Lookup("output").NoOptDefVal = defaultLogFile
+	cmd.Flags().IntVarP(&preload, "preload", "l", 0, "send this many probes back to back at startup")
+	cmd.MarkFlagsMutuallyExclusive("burst", "preload")
+	cmd.Flags().BoolVar(&privileged, "privileged", false, "only send icmp pings over raw sockets")
 	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "only display summary at end")
+	cmd.Flags().BoolVar(&reload, "reload", false, "re-read the targets file on SIGHUP")
+	cmd.Flags().DurationVar(&reorderWindow, "reorder-window", 500*time.Millisecond, "time to wait for an overtaken packet before declaring it lost")
+	cmd.Flags().DurationVarP(&replyTimeout, "reply-timeout", "W", 2*time.Second, "time to wait for a reply before declaring a packet lost")
+	cmd.Flags().DurationVar(&resolveEvery, "resolve-every", 0, "re-resolve hosts this often, and follow them to any new address")
 	cmd.Flags().IntVarP(&size, "size", "s", 56, "size of payload, in bytes")
+	cmd.Flags().IntVar(&tcpPort, "tcp", 0, "time tcp connections to this port instead of sending icmp pings")
+	cmd.Flags().StringVar(&source, "source", "", "send probes from this address")
+	cmd.Flags().StringVar(&sni, "sni", "", "server name to send to tls targets (default the target's hostname)")
 	cmd.Flags().DurationVarP(&timeout, "timeout", "w", time.Duration(math.MaxInt64), "timeout before ping exits, regardless of number of packets sent or received")
 	cmd.Flags().BoolVarP(&timestamp, "timestamp", "t", true, "prepend timestamps to output")
+	cmd.Flags().StringVar(&targetsFile, "targets-file", "", "read additional targets, one per line, from this file")
+	cmd.Flags().StringVar(&timestampFormat, "timestamp-format", TimestampDefault, "timestamp format (default, rfc3339, unix, elapsed, or a Go time layout)")
+	cmd.Flags().IntVar(&tos, "tos", 0, "set the type of service byte (or ipv6 traffic class) of probes")
 	cmd.Flags().IntVarP(&ttl, "ttl", "T", 128, "maximum time-to-live")
+	cmd.Flags().BoolVar(&unprivileged, "unprivileged", false, "only send icmp pings over unprivileged datagram sockets")
+	cmd.MarkFlagsMutuallyExclusive("privileged", "unprivileged")
+	cmd.Flags().BoolVar(&utc, "utc", false, "display timestamps in UTC instead of local time")
 	cmd.Flags().BoolVarP(&version, "version", "V", false, "display version and exit")
+	cmd.Flags().BoolVar(&waitDNS, "wait-for-dns", false, "keep retrying hosts that fail to resolve at startup, instead of exiting")
+	cmd.Flags().StringSliceVar(&via, "via", nil, "probe each target over each of these interfaces or source addresses, side by side")
+	cmd.MarkFlagsMutuallyExclusive("via", "interface")
+	cmd.MarkFlagsMutuallyExclusive("via", "source")
+	cmd.MarkFlagsMutuallyExclusive("dscp", "tos")
 
 	cmd.CompletionOptions.HiddenDefaultCmd = true
 
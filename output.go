@@ -0,0 +1,182 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const defaultLogFile string = "<hostname>.log"
+
+// Shared by every Output, so lines from concurrent targets never interleave on stdout
+var printMu sync.Mutex
+
+type Output struct {
+	file          *os.File
+	regex         *regexp.Regexp
+	headerWritten bool
+}
+
+func logFileName(host string) string {
+	if output == defaultLogFile {
+		return host + ".log"
+	}
+
+	return output
+}
+
+func confirmOverwrite(path string) (bool, error) {
+	_, err := fmt.Fprintf(os.Stderr, "%s already exists. Overwrite? [y/N] ", path)
+	if err != nil {
+		return false, err
+	}
+
+	var answer strings.Builder
+
+	// Read a byte at a time so nothing meant for the statistics reader is consumed;
+	// a bare <Return> or a closed stdin both count as "no"
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 0 || err != nil || buf[0] == '\n' {
+			break
+		}
+
+		answer.WriteByte(buf[0])
+	}
+
+	reply := strings.ToLower(strings.TrimSpace(answer.String()))
+
+	return reply == "y" || reply == "yes", nil
+}
+
+// openLogFile opens path for writing; reopen skips the overwrite checks for a file
+// already written to earlier in the run, and appends to it instead.
+func openLogFile(path string, reopen bool) (*os.File, error) {
+	flags := os.O_CREATE | os.O_WRONLY
+
+	switch {
+	case appendLog || reopen:
+		flags |= os.O_APPEND
+	case force:
+		flags |= os.O_TRUNC
+	default:
+		_, err := os.Stat(path)
+		switch {
+		case err == nil:
+			overwrite, err := confirmOverwrite(path)
+			if err != nil {
+				return nil, err
+			}
+
+			if !overwrite {
+				return nil, ErrOverwriteDeclined
+			}
+		case !os.IsNotExist(err):
+			return nil, err
+		}
+
+		flags |= os.O_TRUNC
+	}
+
+	return os.OpenFile(path, flags, 0644)
+}
+
+func newOutput(host string, reopen bool) (*Output, error) {
+	out := &Output{}
+
+	if output == "" {
+		return out, nil
+	}
+
+	file, err := openLogFile(logFileName(host), reopen)
+	if err != nil {
+		return nil, err
+	}
+
+	out.file = file
+
+	if !logColor {
+		out.regex = regexp.MustCompile(escapeSequences)
+	}
+
+	return out, nil
+}
+
+func (o *Output) Printf(format string, a ...any) error {
+	return o.write(fmt.Sprintf(format, a...), true)
+}
+
+// Logf writes only to the log file, for when stdout is showing something else.
+func (o *Output) Logf(format string, a ...any) error {
+	return o.write(fmt.Sprintf(format, a...), false)
+}
+
+func (o *Output) write(line string, toStdout bool) error {
+	printMu.Lock()
+	defer printMu.Unlock()
+
+	if toStdout {
+		_, err := fmt.Print(line)
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.file == nil {
+		return nil
+	}
+
+	if o.regex != nil {
+		line = Strip(line, o.regex)
+	}
+
+	_, err := o.file.WriteString(line)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Event writes v as a single line of JSON.
+func (o *Output) Event(v any) error {
+	line, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return o.Printf("%s\n", line)
+}
+
+// Record writes fields as a single CSV row.
+func (o *Output) Record(fields []string) error {
+	var s strings.Builder
+
+	w := csv.NewWriter(&s)
+
+	err := w.Write(fields)
+	if err != nil {
+		return err
+	}
+
+	w.Flush()
+
+	return o.Printf("%s", s.String())
+}
+
+func (o *Output) Close() error {
+	if o.file == nil {
+		return nil
+	}
+
+	return o.file.Close()
+}
@@ -6,25 +6,23 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/signal"
-	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/fatih/color"
 	ping "github.com/prometheus-community/pro-bing"
 )
 
-const DATE string = "2006-01-02 15:04:05.000 MST"
-
-type Packets struct {
-	Expected int
-	Current  int
-}
-
 func humanReadableSize(bytes int) string {
 	const unit = 1000
 
@@ -43,150 +41,413 @@ func humanReadableSize(bytes int) string {
 		float64(bytes)/float64(div), "kMGTPE"[exp])
 }
 
-func configurePinger(pinger *ping.Pinger) error {
+func configurePinger(pinger *ping.Pinger, target *Target) error {
 	pinger.Count = count
-	pinger.Size = size
-	pinger.Interval = interval
+	pinger.Size = target.Size
+	pinger.Interval = target.Interval
 	pinger.Timeout = timeout
 	pinger.TTL = ttl
 	pinger.RecordRtts = false
 
-	// Running in privileged mode is required to send ICMP pings instead of UDP "pings"
-	pinger.SetPrivileged(true)
+	pinger.SetNetwork(target.Network)
 
-	switch {
-	case ipv4:
-		pinger.SetNetwork("ip4")
-	case ipv6:
-		pinger.SetNetwork("ip6")
-	default:
-		pinger.SetNetwork("ip")
+	target.Binding.apply(pinger)
+
+	if target.Address != "" {
+		pinger.SetIPAddr(&net.IPAddr{IP: net.ParseIP(target.Address)})
+	} else {
+		pinger.ResolveTimeout = resolveTimeout
+
+		err := waitForDNS(target.Host, pinger.Resolve)
+		if err != nil {
+			return err
+		}
 	}
 
-	err := pinger.Resolve()
+	// Both modes send real ICMP echo requests; unprivileged mode just lets the kernel fill in the ID
+	privileged, err := icmpPrivileged(pinger.IPAddr().IP.To4() == nil)
 	if err != nil {
 		return err
 	}
 
-	color.NoColor = !colorize
+	pinger.SetPrivileged(privileged)
 
 	return nil
 }
 
-func showReceived(pkt *ping.Packet, pinger *ping.Pinger, packets *Packets, colors *Colors) error {
-	packets.Current = pkt.Seq
+func linePrefix(target *Target) string {
+	var prefix string
 
-	switch {
-	case dropped && timestamp && (packets.Expected != packets.Current):
-		for c := packets.Expected; c < packets.Current; c++ {
-			_, err := fmt.Printf("%s | %s", colors.Grey.Sprint(time.Now().Format(DATE)), colors.Red.Sprintf("Packet %d lost or arrived out of order.\n", c))
-			if err != nil {
-				return err
-			}
+	if timestamp {
+		prefix += target.colors.Grey.Sprint(formatTimestamp(time.Now())) + " | "
+	}
+
+	if target.Label != "" {
+		prefix += target.colors.Label.Sprint(target.Label) + " | "
+	}
+
+	return prefix
+}
+
+func showLost(seq uint64, reason string, target *Target) error {
+	// Flood and burst modes count losses towards their progress and burst reports instead
+	if !dropped || !perProbe() {
+		return nil
+	}
+
+	switch format {
+	case "json":
+		return target.out.Event(newJSONLost(seq, reason, target))
+	case "csv":
+		return target.out.Record(newCSVLost(seq, target))
+	}
+
+	if reason != "" {
+		return target.out.Printf("%s%s", linePrefix(target), target.colors.Red.Sprintf("Packet %d lost: %s.\n", seq, reason))
+	}
+
+	return target.out.Printf("%s%s", linePrefix(target), target.colors.Red.Sprintf("Packet %d lost.\n", seq))
+}
+
+func stopWhenSettled(target *Target) {
+	if count != 0 && target.tracker.Counts().Sent >= probeCount() && target.tracker.Settled() {
+		target.prober.Stop()
+	}
+}
+
+// describeReply returns everything in a reply line up to its round-trip time.
+func describeReply(reply *Reply, seq uint64, target *Target) string {
+	colors := target.colors
+
+	var s strings.Builder
+
+	switch target.Protocol {
+	case ProtocolTCP, ProtocolTLS, ProtocolHTTP, ProtocolDNS:
+		verb := "response from"
+		if target.Protocol == ProtocolTCP || target.Protocol == ProtocolTLS {
+			verb = "connected to"
 		}
-		packets.Expected = packets.Current + 1
-	case dropped && (packets.Expected != packets.Current):
-		for c := packets.Expected; c < packets.Current; c++ {
-			colors.Red.Sprintf("Packet %d lost or arrived out of order.\n", c)
+
+		s.WriteString(fmt.Sprintf("%s %s: %s_seq=%s",
+			verb,
+			colors.Blue.Sprint(net.JoinHostPort(reply.IPAddr.String(), strconv.Itoa(target.Port))),
+			target.Protocol,
+			colors.Blue.Sprintf("%d", seq)))
+	default:
+		s.WriteString(fmt.Sprintf("%s from %s: icmp_seq=%s ttl=%s",
+			colors.Blue.Sprintf("%d bytes", reply.Nbytes-8),
+			colors.Blue.Sprintf("%s", reply.IPAddr),
+			colors.Blue.Sprintf("%d", seq),
+			colors.Blue.Sprintf("%d", reply.TTL)))
+	}
+
+	for _, detail := range reply.Details {
+		value := detail.Value
+
+		duration, ok := value.(time.Duration)
+		if ok {
+			value = duration.Round(time.Microsecond)
 		}
-		packets.Expected = packets.Current + 1
-	case dropped:
-		packets.Expected = packets.Current + 1
-	}
-
-	if timestamp && !quiet {
-		_, err := fmt.Printf("%s | %s from %s: icmp_seq=%s ttl=%s time=%s\n",
-			colors.Grey.Sprint(time.Now().Format(DATE)),
-			colors.Blue.Sprintf("%d bytes", pkt.Nbytes-8),
-			colors.Blue.Sprintf("%s", pkt.IPAddr),
-			colors.Blue.Sprintf("%d", pkt.Seq),
-			colors.Blue.Sprintf("%d", pkt.TTL),
-			highlightLongRTT(pkt.Rtt.Round(time.Microsecond), colors, false))
+
+		s.WriteString(fmt.Sprintf(" %s=%s", detail.Key, highlightDetail(value, detail.Warn, colors)))
+	}
+
+	return s.String()
+}
+
+func showReceived(reply *Reply, target *Target) error {
+	colors := target.colors
+
+	var suffix string
+
+	seq, status := target.tracker.Received(reply.Seq)
+
+	switch status {
+	case ReplyReordered:
+		suffix = " " + colors.Red.Sprintf("(REORDERED)")
+	case ReplyLate:
+		suffix = " " + colors.Red.Sprintf("(LATE!)")
+	}
+
+	// Flood mode reports progress once a second instead, and burst mode each burst as a whole
+	show := !quiet && perProbe()
+
+	if format == "json" && show {
+		event := newJSONReply("reply", reply, seq, target)
+
+		switch status {
+		case ReplyReordered:
+			event.Reordered = true
+		case ReplyLate:
+			event.Type = "late"
+		}
+
+		err := target.out.Event(event)
 		if err != nil {
 			return err
 		}
-	} else if !quiet {
-		_, err := fmt.Printf("%s from %s: icmp_seq=%s ttl=%s time=%s\n",
-			colors.Blue.Sprintf("%d bytes", pkt.Nbytes-8),
-			colors.Blue.Sprintf("%s", pkt.IPAddr),
-			colors.Blue.Sprintf("%d", pkt.Seq),
-			colors.Blue.Sprintf("%d", pkt.TTL),
-			highlightLongRTT(pkt.Rtt.Round(time.Microsecond), colors, false))
+	} else if format == "csv" && show {
+		outcome := "ok"
+
+		switch status {
+		case ReplyReordered:
+			outcome = "reordered"
+		case ReplyLate:
+			outcome = "late"
+		}
+
+		err := target.out.Record(newCSVReply(outcome, reply.Packet, seq, target))
+		if err != nil {
+			return err
+		}
+	} else if show {
+		err := target.out.Printf("%s%s time=%s%s\n",
+			linePrefix(target),
+			describeReply(reply, seq, target),
+			highlightLongRTT(reply.Rtt.Round(time.Microsecond), target.MaxRtt, colors, false),
+			suffix)
 		if err != nil {
 			return err
 		}
 	}
 
-	if packets.Current == (count - 1) {
-		pinger.Stop()
-	}
-
-	return nil
-}
-
-func showDuplicate(pkt *ping.Packet, colors *Colors) error {
-	if timestamp {
-		_, err := fmt.Printf("%s | %s from %s: icmp_seq=%s ttl=%s time=%s %s\n",
-			colors.Grey.Sprint(time.Now().Format(DATE)),
-			colors.Blue.Sprintf("%d bytes", pkt.Nbytes-8),
-			colors.Blue.Sprintf("%s", pkt.IPAddr),
-			colors.Blue.Sprintf("%d", pkt.Seq),
-			colors.Blue.Sprintf("%d", pkt.TTL),
-			highlightLongRTT(pkt.Rtt.Round(time.Microsecond), colors, false),
-			colors.Red.Sprintf("(DUP!)"))
+	if target.comparison != nil && status != ReplyLate {
+		err := target.comparison.settle(target, seq, reply.Rtt, false)
 		if err != nil {
 			return err
 		}
-	} else {
-		_, err := fmt.Printf("%s from %s: icmp_seq=%s ttl=%s time=%s %s\n",
-			colors.Blue.Sprintf("%d bytes", pkt.Nbytes-8),
-			colors.Blue.Sprintf("%s", pkt.IPAddr),
-			colors.Blue.Sprintf("%d", pkt.Seq),
-			colors.Blue.Sprintf("%d", pkt.TTL),
-			highlightLongRTT(pkt.Rtt.Round(time.Microsecond), colors, false),
-			colors.Red.Sprintf("(DUP!)"))
+	}
+
+	// A late reply's probe was already counted towards its burst as lost
+	if status != ReplyLate {
+		err := settleBurst(seq, reply.Rtt, false, target)
 		if err != nil {
 			return err
 		}
 	}
 
+	stopWhenSettled(target)
+
 	return nil
 }
 
-func showStatistics(stats *ping.Statistics, pinger *ping.Pinger, packets *Packets, colors *Colors, startTime time.Time, wasInterrupted bool, isEnding bool) string {
-	var s strings.Builder
+func showDuplicate(pkt *ping.Packet, target *Target) error {
+	colors := target.colors
 
-	if isEnding && !wasInterrupted && dropped && count != 0 && (packets.Current != (count - 1)) {
-		for c := packets.Current + 1; c < count; c++ {
-			s.WriteString(colors.Red.Sprintf("Packet %d lost or arrived out of order.\n", c))
+	seq := target.tracker.Extend(pkt.Seq)
+
+	switch format {
+	case "json":
+		return target.out.Event(newJSONReply("duplicate", &Reply{Packet: pkt}, seq, target))
+	case "csv":
+		// Rows in burst mode are whole bursts, which duplicates have no place among
+		if burst > 1 {
+			return nil
 		}
+
+		return target.out.Record(newCSVReply("dup", pkt, seq, target))
+	}
+
+	return target.out.Printf("%s%s time=%s %s\n",
+		linePrefix(target),
+		describeReply(&Reply{Packet: pkt}, seq, target),
+		highlightLongRTT(pkt.Rtt.Round(time.Microsecond), target.MaxRtt, colors, false),
+		colors.Red.Sprintf("(DUP!)"))
+}
+
+func showStatistics(stats *ping.Statistics, target *Target, isEnding bool) string {
+	colors := target.colors
+
+	var s strings.Builder
+
+	s.WriteString(fmt.Sprintf("--- %v ping statistics ---\n", colors.Green.Sprint(target.Name)))
+
+	elapsed := time.Since(target.startTime).Round(time.Millisecond)
+
+	if target.Protocol == ProtocolICMP {
+		s.WriteString(fmt.Sprintf("%s packets transmitted (%s), %s packets received (%s), %s packet loss, time %s\n",
+			colors.Blue.Sprintf("%d", stats.PacketsSent),
+			colors.Blue.Sprint(humanReadableSize(stats.PacketsSent*target.Size)),
+			colors.Blue.Sprintf("%d", stats.PacketsRecv),
+			colors.Blue.Sprint(humanReadableSize(stats.PacketsRecv*target.Size)),
+			highlightPacketLoss(stats.PacketLoss, colors),
+			colors.Blue.Sprintf("%s", elapsed)))
+	} else {
+		// Only ICMP probes have a payload size worth reporting
+		s.WriteString(fmt.Sprintf("%s packets transmitted, %s packets received, %s packet loss, time %s\n",
+			colors.Blue.Sprintf("%d", stats.PacketsSent),
+			colors.Blue.Sprintf("%d", stats.PacketsRecv),
+			highlightPacketLoss(stats.PacketLoss, colors),
+			colors.Blue.Sprintf("%s", elapsed)))
 	}
 
-	s.WriteString(fmt.Sprintf("--- %v ping statistics ---\n", colors.Green.Sprint(stats.Addr)))
+	counts := target.tracker.Counts()
+
+	s.WriteString(fmt.Sprintf("%s lost, %s reordered, %s late, %s duplicates\n",
+		highlightCount(counts.Lost, colors),
+		highlightCount(counts.Reordered, colors),
+		highlightCount(counts.Late, colors),
+		highlightCount(stats.PacketsRecvDuplicates, colors)))
+
+	if flood {
+		sendRate, replyRate := floodRates(stats, target)
+
+		s.WriteString(fmt.Sprintf("rate sent/received = %s/%s pps, %s loss under load\n",
+			colors.Blue.Sprintf("%.0f", sendRate),
+			colors.Blue.Sprintf("%.0f", replyRate),
+			highlightPacketLoss(lossUnderLoad(target), colors)))
+	}
 
-	s.WriteString(fmt.Sprintf("%s packets transmitted (%s), %s packets received (%s), %s packet loss, time %s\n",
-		colors.Blue.Sprintf("%d", stats.PacketsSent),
-		colors.Blue.Sprint(humanReadableSize(stats.PacketsSent*pinger.Size)),
-		colors.Blue.Sprintf("%d", stats.PacketsRecv),
-		colors.Blue.Sprint(humanReadableSize(stats.PacketsRecv*pinger.Size)),
-		highlightPacketLoss(stats.PacketLoss, colors),
-		colors.Blue.Sprintf("%s", time.Since(startTime).Round(time.Millisecond))))
+	if target.bursts != nil {
+		s.WriteString(showBurstStatistics(target))
+	}
 
 	s.WriteString(fmt.Sprintf("round-trip min/avg/max/stddev = %s/%s/%s/%s\n\n",
-		highlightLongRTT(stats.MinRtt.Round(time.Microsecond), colors, true),
-		highlightLongRTT(stats.AvgRtt.Round(time.Microsecond), colors, true),
-		highlightLongRTT(stats.MaxRtt.Round(time.Microsecond), colors, true),
+		highlightLongRTT(stats.MinRtt.Round(time.Microsecond), target.MaxRtt, colors, true),
+		highlightLongRTT(stats.AvgRtt.Round(time.Microsecond), target.MaxRtt, colors, true),
+		highlightLongRTT(stats.MaxRtt.Round(time.Microsecond), target.MaxRtt, colors, true),
 		colors.Blue.Sprintf("%v", stats.StdDevRtt.Round(time.Microsecond))))
 
 	return s.String()
 }
 
-func showStart(pinger *ping.Pinger, colors *Colors) error {
-	_, err := fmt.Printf("PING %s (%s) %s(%s) bytes of data.\n",
-		colors.Green.Sprintf("%s", pinger.Addr()),
-		colors.Blue.Sprintf("%s", pinger.IPAddr()),
-		colors.Blue.Sprintf("%d", size),
-		colors.Blue.Sprintf("%d", size+28))
+// showSummaryTable condenses the statistics for several targets into one row each, in the style of fping -s.
+func showSummaryTable(targets []*Target) string {
+	var s strings.Builder
+
+	w := tabwriter.NewWriter(&s, 0, 0, 2, ' ', 0)
+
+	header := "HOST\tADDRESS\tSENT\tRECV\tLOSS\tLOST\tREORD\tLATE\tDUP\tMIN\tAVG\tMAX\tSTDDEV"
+	if flood {
+		header += "\tTX PPS\tRX PPS"
+	}
+
+	if burst > 1 {
+		header += "\tBURSTS\tLOSSY\tMEDIAN P50\tMEDIAN P95\tSPREAD P50\tSPREAD P95"
+	}
+
+	fmt.Fprintln(w, header)
+
+	for _, target := range targets {
+		stats := target.prober.Statistics()
+		counts := target.tracker.Counts()
+
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%.3f%%\t%d\t%d\t%d\t%d\t%s\t%s\t%s\t%s",
+			target.Name,
+			target.prober.IPAddr(),
+			stats.PacketsSent,
+			stats.PacketsRecv,
+			stats.PacketLoss,
+			counts.Lost,
+			counts.Reordered,
+			counts.Late,
+			stats.PacketsRecvDuplicates,
+			stats.MinRtt.Round(time.Microsecond),
+			stats.AvgRtt.Round(time.Microsecond),
+			stats.MaxRtt.Round(time.Microsecond),
+			stats.StdDevRtt.Round(time.Microsecond))
+
+		if flood {
+			sendRate, replyRate := floodRates(stats, target)
+
+			fmt.Fprintf(w, "\t%.0f\t%.0f", sendRate, replyRate)
+		}
+
+		if target.bursts != nil {
+			summary := target.bursts.Summary()
+
+			fmt.Fprintf(w, "\t%d\t%d\t%s\t%s\t%s\t%s",
+				summary.Bursts,
+				summary.Lossy,
+				summary.MedianP50.Round(time.Microsecond),
+				summary.MedianP95.Round(time.Microsecond),
+				summary.SpreadP50.Round(time.Microsecond),
+				summary.SpreadP95.Round(time.Microsecond))
+		}
+
+		fmt.Fprintln(w)
+	}
+
+	w.Flush()
+
+	s.WriteString("\n")
+
+	return s.String()
+}
+
+func showStart(target *Target) error {
+	colors := target.colors
+
+	switch format {
+	case "json":
+		return target.out.Event(newJSONStart(target))
+	case "csv":
+		// Targets sharing a file share a header
+		if target.out.headerWritten {
+			return nil
+		}
+
+		target.out.headerWritten = true
+
+		// Burst mode logs a row per burst, rather than per probe
+		if burst > 1 {
+			return target.out.Record(csvBurstHeader)
+		}
+
+		return target.out.Record(csvHeader)
+	}
+
+	// Match the host to the label on each of its lines
+	host := colors.Green
+	if target.Label != "" {
+		host = colors.Label
+	}
+
+	switch target.Protocol {
+	case ProtocolTCP:
+		return target.out.Printf("TCP PING %s (%s) port %s%s.\n",
+			host.Sprintf("%s", target.Host),
+			colors.Blue.Sprintf("%s", target.prober.IPAddr()),
+			colors.Blue.Sprintf("%d", target.Port),
+			target.Binding.banner(colors))
+	case ProtocolTLS:
+		serverName := ""
+		if target.ServerName != "" {
+			serverName = " SNI " + colors.Blue.Sprint(target.ServerName)
+		}
+
+		return target.out.Printf("TLS PING %s (%s) port %s%s%s.\n",
+			host.Sprintf("%s", target.Host),
+			colors.Blue.Sprintf("%s", target.prober.IPAddr()),
+			colors.Blue.Sprintf("%d", target.Port),
+			serverName,
+			target.Binding.banner(colors))
+	case ProtocolDNS:
+		return target.out.Printf("DNS PING %s (%s) port %s%s: %s %s.\n",
+			host.Sprintf("%s", target.Host),
+			colors.Blue.Sprintf("%s", target.prober.IPAddr()),
+			colors.Blue.Sprintf("%d", target.Port),
+			target.Binding.banner(colors),
+			colors.Blue.Sprintf("%s", target.QueryName),
+			colors.Blue.Sprintf("%s", strings.ToUpper(target.QueryType)))
+	case ProtocolHTTP:
+		return target.out.Printf("HTTP PING %s (%s)%s.\n",
+			host.Sprintf("%s", target.URL),
+			colors.Blue.Sprintf("%s", target.prober.IPAddr()),
+			target.Binding.banner(colors))
+	}
+
+	source := target.Binding.banner(colors)
+	if source != "" {
+		source += ":"
+	}
+
+	err := target.out.Printf("PING %s (%s)%s %s(%s) bytes of data.\n",
+		host.Sprintf("%s", target.Host),
+		colors.Blue.Sprintf("%s", target.prober.IPAddr()),
+		source,
+		colors.Blue.Sprintf("%d", target.Size),
+		colors.Blue.Sprintf("%d", target.Size+28))
 	if err != nil {
 		return err
 	}
@@ -194,7 +455,90 @@ func showStart(pinger *ping.Pinger, colors *Colors) error {
 	return nil
 }
 
-func pingCmd(arguments []string) error {
+// showAddressChanged notes that target's host now resolves elsewhere, and will be probed there from now on.
+func showAddressChanged(previous, ipaddr *net.IPAddr, target *Target) error {
+	switch format {
+	case "json":
+		return target.out.Event(newJSONAddress(previous, ipaddr, target))
+	case "csv":
+		return nil
+	}
+
+	return target.out.Printf("%s%s\n", linePrefix(target), target.colors.Red.Sprintf("Address changed from %s to %s.", previous, ipaddr))
+}
+
+// showResolveFailed notes that re-resolving target's host failed, so it is still probed at its last known address.
+func showResolveFailed(err error, target *Target) error {
+	if format != "text" {
+		return nil
+	}
+
+	return target.out.Printf("%s%s\n", linePrefix(target), target.colors.Grey.Sprintf("Failed to re-resolve %s: %s.", target.Host, failureReason(err)))
+}
+
+// showRemoved notes that a target has been dropped from the targets file, before it stops.
+func showRemoved(target *Target) error {
+	if format != "text" {
+		return nil
+	}
+
+	return target.out.Printf("%s%s\n", linePrefix(target), target.colors.Grey.Sprint("Removed from targets file."))
+}
+
+// showRestarted notes that a target's options in the targets file have changed, before it restarts with them.
+func showRestarted(target *Target) error {
+	if format != "text" {
+		return nil
+	}
+
+	return target.out.Printf("%s%s\n", linePrefix(target), target.colors.Grey.Sprint("Options changed; restarting."))
+}
+
+// showFinalStatistics prints the end-of-run summary for a single target.
+func showFinalStatistics(stats *ping.Statistics, target *Target, labelled bool) error {
+	switch {
+	case format == "json":
+		return target.out.Event(newJSONSummary(stats, target, true))
+	case labelled:
+		// Covered by the summary table once every target has finished
+		return nil
+	case format == "csv":
+		// Keep stdout and the log file parseable as CSV
+		_, err := fmt.Fprintf(os.Stderr, "\n%s", showStatistics(stats, target, true))
+
+		return err
+	default:
+		return target.out.Printf("\n%s", showStatistics(stats, target, true))
+	}
+}
+
+// showCurrentStatistics prints the running statistics for every target to stderr, when <Return> is pressed.
+func showCurrentStatistics(targets []*Target, labelled bool) error {
+	switch {
+	case format == "json":
+		encoder := json.NewEncoder(os.Stderr)
+
+		for _, target := range targets {
+			err := encoder.Encode(newJSONSummary(target.prober.Statistics(), target, false))
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case labelled:
+		_, err := fmt.Fprint(os.Stderr, showSummaryTable(targets))
+
+		return err
+	default:
+		_, err := fmt.Fprint(os.Stderr, showStatistics(targets[0].prober.Statistics(), targets[0], false))
+
+		return err
+	}
+}
+
+// setTimeZone applies TZ and --utc to every timestamp printed from now on.
+func setTimeZone() error {
 	timeZone := os.Getenv("TZ")
 	if timeZone != "" {
 		var err error
@@ -205,115 +549,155 @@ func pingCmd(arguments []string) error {
 		}
 	}
 
-	var host string = ""
-
-	if net.ParseIP(arguments[0]) != nil {
-		host = arguments[0]
-	} else {
-		url := regexp.MustCompile(`^([^:\/]+://)?([^:\/]+)(.+)?$`)
-
-		asUrl := url.FindAllStringSubmatch(arguments[0], -1)
-		if len(asUrl) > 0 && len(asUrl[0]) > 1 {
-			host = asUrl[0][2]
-		}
+	if utc {
+		time.Local = time.UTC
 	}
 
-	var startTime = time.Time{}
-	var wasInterrupted = false
+	return nil
+}
 
-	pinger, err := ping.NewPinger(host)
+func pingCmd(arguments []string) error {
+	err := setTimeZone()
 	if err != nil {
 		return err
 	}
 
-	err = configurePinger(pinger)
-	if err != nil {
-		return err
-	}
+	color.NoColor = !colorize
 
-	colors := &Colors{
-		Blue:  color.New(color.FgBlue),
-		Green: color.New(color.FgGreen),
-		Grey:  color.New(color.FgHiBlack),
-		Red:   color.New(color.FgRed),
-	}
+	var targets []*Target
 
-	packets := &Packets{
-		Expected: 0,
-		Current:  0,
+	for _, resolver := range dnsResolvers {
+		arguments = append(arguments, dnsTarget(resolver))
 	}
 
-	errorChannel := make(chan error)
-	done := make(chan bool, 1)
-
-	pinger.OnRecv = func(pkt *ping.Packet) {
-		err := showReceived(pkt, pinger, packets, colors)
+	for _, argument := range arguments {
+		target, err := newTarget(argument)
 		if err != nil {
-			errorChannel <- err
+			return err
 		}
+
+		targets = append(targets, target)
 	}
 
-	pinger.OnDuplicateRecv = func(pkt *ping.Packet) {
-		err := showDuplicate(pkt, colors)
+	targets, err = expandVariants(targets)
+	if err != nil {
+		return err
+	}
+
+	if targetsFile != "" {
+		fromFile, err := readTargetsFile(targetsFile)
 		if err != nil {
-			errorChannel <- err
+			return err
 		}
+
+		targets = append(targets, fromFile...)
+	}
+
+	err = checkDuplicateNames(targets)
+	if err != nil {
+		return err
+	}
+
+	if len(targets) == 0 {
+		return ErrNoTargets
 	}
 
-	pinger.OnFinish = func(stats *ping.Statistics) {
-		fmt.Printf("\n%s", showStatistics(stats, pinger, packets, colors, startTime, wasInterrupted, true))
+	// Friendly names from a targets file are shown even when there is only one target, as are
+	// variants when the others could not be probed
+	labelled := len(targets) > 1 || targetsFile != "" || slices.ContainsFunc(targets, func(target *Target) bool {
+		return target.comparison != nil
+	})
+
+	session := newSession(labelled)
+	defer session.Close()
 
-		done <- true
+	startedAt = time.Now()
+
+	for _, target := range targets {
+		err := session.Start(target)
+		if err != nil {
+			session.StopAll()
+
+			return err
+		}
 	}
 
-	showStart(pinger, colors)
+	session.Reopen()
 
 	interrupt := make(chan os.Signal, 1)
 	signal.Notify(interrupt, os.Interrupt)
 	go func() {
 		for {
 			<-interrupt
-			wasInterrupted = true
-			pinger.Stop()
+			session.Interrupt()
 		}
 	}()
 
+	if reload {
+		hangup := make(chan os.Signal, 1)
+		signal.Notify(hangup, syscall.SIGHUP)
+		go func() {
+			for {
+				<-hangup
+
+				// A broken targets file should not take down the targets already running
+				err := reloadTargets(session)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to reload targets: %v\n", err)
+				}
+			}
+		}()
+	}
+
 	go func() {
 		consoleReader := bufio.NewReaderSize(os.Stdin, 1)
 		for {
 			input, _, err := consoleReader.ReadRune()
+			if err == io.EOF {
+				// Nothing more will arrive, e.g. when stdin is /dev/null
+				return
+			}
 			if err != nil {
-				errorChannel <- err
+				session.report(err)
 
 				continue
 			}
 
 			if string(input) == "\n" {
-				fmt.Fprint(os.Stderr, showStatistics(pinger.Statistics(), pinger, packets, colors, startTime, false, false))
+				session.report(showCurrentStatistics(session.Targets(), session.labelled))
 			}
 		}
 	}()
 
-	startTime = time.Now()
-
-	go func() {
-		err = pinger.Run()
-		if err != nil {
-			errorChannel <- err
-		}
-	}()
-
-Poll:
 	for {
 		select {
-		case err := <-errorChannel:
-			pinger.Stop()
+		case err := <-session.errors:
+			session.StopAll()
 
 			return err
-		case <-done:
-			break Poll
+		case target := <-session.done:
+			if session.Finished(target) > 0 {
+				continue
+			}
 		}
+
+		break
 	}
 
-	return nil
+	targets = session.Targets()
+
+	switch {
+	case !session.labelled || format == "json":
+		return nil
+	case format == "csv":
+		// Keep stdout and the log file parseable as CSV
+		_, err := fmt.Fprintf(os.Stderr, "\n%s%s", showSummaryTable(targets), showComparisons(targets))
+
+		return err
+	case session.shared != nil:
+		return session.shared.Printf("\n%s%s", showSummaryTable(targets), showComparisons(targets))
+	default:
+		// With a log file per target, the table spanning all of them only goes to stdout
+		return (&Output{}).Printf("\n%s%s", showSummaryTable(targets), showComparisons(targets))
+	}
 }
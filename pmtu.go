@@ -0,0 +1,446 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	ping "github.com/prometheus-community/pro-bing"
+)
+
+const (
+	// Pings sent at each size before deciding it does not fit
+	pmtuAttempts int = 3
+
+	pmtuSpacing time.Duration = 100 * time.Millisecond
+
+	// IP and ICMP headers, which count towards the MTU but not the ping size
+	ipv4Overhead int = 20 + 8
+	ipv6Overhead int = 40 + 8
+
+	// The smallest MTUs each protocol allows a link to have, and the largest packet either can send
+	ipv4MinMTU int = 68
+	ipv6MinMTU int = 1280
+	maxMTU     int = 65535
+)
+
+// pmtuPath tracks the path MTU to a host over one address family.
+type pmtuPath struct {
+	family string
+	ipaddr *net.IPAddr
+	v6     bool
+
+	// The MTU of the interface the path leaves by, which it can never exceed
+	ceiling int
+
+	mtu    int
+	lowest int
+
+	// Whether the last search was told packets were too big, rather than them just disappearing
+	reported bool
+}
+
+func newPMTUPath(ipaddr *net.IPAddr) *pmtuPath {
+	path := &pmtuPath{
+		family:  "IPv4",
+		ipaddr:  ipaddr,
+		v6:      ipaddr.IP.To4() == nil,
+		ceiling: interfaceMTU(ipaddr.IP),
+	}
+
+	if path.v6 {
+		path.family = "IPv6"
+	}
+
+	return path
+}
+
+func (p *pmtuPath) floor() int {
+	if p.v6 {
+		return ipv6MinMTU
+	}
+
+	return ipv4MinMTU
+}
+
+func (p *pmtuPath) overhead() int {
+	if p.v6 {
+		return ipv6Overhead
+	}
+
+	return ipv4Overhead
+}
+
+// interfaceMTU returns the MTU of the interface traffic to ip would leave by, or 1500 if that can't be worked out.
+func interfaceMTU(ip net.IP) int {
+	if name := interfaceName(); name != "" {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return 1500
+		}
+
+		return min(iface.MTU, maxMTU)
+	}
+
+	// Connecting a UDP socket sends nothing, but does pick the route
+	conn, err := Binding{Source: sourceAddress()}.dialer("udp").Dial("udp", net.JoinHostPort(ip.String(), "9"))
+	if err != nil {
+		return 1500
+	}
+	defer conn.Close()
+
+	local := conn.LocalAddr().(*net.UDPAddr).IP
+
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return 1500
+	}
+
+	for _, iface := range interfaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+
+		for _, addr := range addrs {
+			network, ok := addr.(*net.IPNet)
+			if ok && network.IP.Equal(local) {
+				return min(iface.MTU, maxMTU)
+			}
+		}
+	}
+
+	return 1500
+}
+
+// fits reports whether a ping of exactly mtu bytes, with the Don't Fragment bit set, gets a reply;
+// tooBig is set when the kernel refused to send it, having been told the path is narrower.
+func (p *pmtuPath) fits(mtu int) (ok bool, tooBig bool, err error) {
+	pinger := ping.New("")
+	pinger.SetIPAddr(p.ipaddr)
+	pinger.SetDoNotFragment(true)
+
+	globalBinding().apply(pinger)
+
+	pinger.Count = pmtuAttempts
+	pinger.Size = mtu - p.overhead()
+	pinger.Interval = pmtuSpacing
+	pinger.Timeout = time.Duration(pmtuAttempts-1)*pmtuSpacing + replyTimeout
+	pinger.RecordRtts = false
+	pinger.RecordTTLs = false
+
+	privileged, err := icmpPrivileged(p.v6)
+	if err != nil {
+		return false, false, err
+	}
+
+	pinger.SetPrivileged(privileged)
+
+	pinger.OnRecv = func(*ping.Packet) {
+		pinger.Stop()
+	}
+
+	err = pinger.Run()
+	if errors.Is(err, syscall.EMSGSIZE) {
+		return false, true, nil
+	}
+	if err != nil {
+		return false, false, err
+	}
+
+	return pinger.Statistics().PacketsRecv > 0, false, nil
+}
+
+// search binary-searches for the largest MTU from low to high that fits, given that low does.
+func (p *pmtuPath) search(low, high int) (int, error) {
+	p.reported = false
+
+	for low < high {
+		mid := low + (high-low+1)/2
+
+		ok, tooBig, err := p.fits(mid)
+		if err != nil {
+			return 0, err
+		}
+
+		if tooBig {
+			p.reported = true
+		}
+
+		if ok {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+
+	return low, nil
+}
+
+// cause explains why the path MTU is lower than the interface's, if it is.
+func (p *pmtuPath) cause(colors *Colors) string {
+	switch {
+	case p.mtu >= p.ceiling:
+		return ""
+	case p.reported:
+		return " (larger packets are reported as too big, so path MTU discovery works)"
+	default:
+		return colors.Red.Sprint(" (larger packets vanish without a Fragmentation Needed message: likely an MTU black hole)")
+	}
+}
+
+// PMTU discovers, and optionally keeps an eye on, the path MTU to a host over IPv4 and IPv6.
+type PMTU struct {
+	host   string
+	paths  []*pmtuPath
+	out    *Output
+	colors *Colors
+}
+
+func newPMTU(host string) (*PMTU, error) {
+	var ipaddrs []net.IPAddr
+
+	ip := net.ParseIP(host)
+	if ip != nil {
+		ipaddrs = []net.IPAddr{{IP: ip}}
+	} else {
+		var err error
+
+		ipaddrs, err = net.DefaultResolver.LookupIPAddr(context.Background(), host)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pmtu := &PMTU{
+		host:   host,
+		colors: newColors(0),
+	}
+
+	var found4, found6 bool
+
+	for _, ipaddr := range ipaddrs {
+		v6 := ipaddr.IP.To4() == nil
+
+		switch {
+		case v6 && (found6 || ipv4):
+			continue
+		case !v6 && (found4 || ipv6):
+			continue
+		case v6:
+			found6 = true
+		default:
+			found4 = true
+		}
+
+		pmtu.paths = append(pmtu.paths, newPMTUPath(&ipaddr))
+	}
+
+	if len(pmtu.paths) == 0 {
+		return nil, fmt.Errorf("no usable address found for %s", host)
+	}
+
+	out, err := newOutput(host, false)
+	if err != nil {
+		return nil, err
+	}
+
+	pmtu.out = out
+
+	return pmtu, nil
+}
+
+func (m *PMTU) logf(path *pmtuPath, format string, a ...any) error {
+	var prefix string
+
+	if timestamp {
+		prefix += m.colors.Grey.Sprint(formatTimestamp(time.Now())) + " | "
+	}
+
+	if path != nil {
+		prefix += m.colors.Label.Sprint(path.family) + " | "
+	}
+
+	return m.out.Printf("%s%s", prefix, fmt.Sprintf(format, a...))
+}
+
+// discover finds the path MTU from scratch.
+func (m *PMTU) discover(path *pmtuPath) error {
+	ok, _, err := path.fits(path.floor())
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		path.mtu = 0
+
+		return m.logf(path, "%s", m.colors.Red.Sprintf("No reply from %s, even at %d bytes.\n", path.ipaddr, path.floor()))
+	}
+
+	path.mtu, err = path.search(path.floor(), path.ceiling)
+	if err != nil {
+		return err
+	}
+
+	if path.lowest == 0 || path.mtu < path.lowest {
+		path.lowest = path.mtu
+	}
+
+	return m.logf(path, "Path MTU to %s is %s bytes%s.\n",
+		path.ipaddr, m.colors.Blue.Sprint(path.mtu), path.cause(m.colors))
+}
+
+// check confirms the path MTU still fits, and searches for its new value if not.
+func (m *PMTU) check(path *pmtuPath) error {
+	if path.mtu == 0 {
+		return m.discover(path)
+	}
+
+	ok, _, err := path.fits(path.mtu)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		// Tell a narrower path apart from one that is down altogether
+		ok, _, err = path.fits(path.floor())
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			path.mtu = 0
+
+			return m.logf(path, "%s", m.colors.Red.Sprintf("No reply from %s, even at %d bytes.\n", path.ipaddr, path.floor()))
+		}
+
+		previous := path.mtu
+
+		path.mtu, err = path.search(path.floor(), previous-1)
+		if err != nil {
+			return err
+		}
+
+		path.lowest = min(path.lowest, path.mtu)
+
+		return m.logf(path, "%s%s.\n",
+			m.colors.Red.Sprintf("Path MTU to %s shrank from %d to %d bytes", path.ipaddr, previous, path.mtu), path.cause(m.colors))
+	}
+
+	if path.mtu >= path.ceiling {
+		return nil
+	}
+
+	ok, _, err = path.fits(path.mtu + 1)
+	if err != nil || !ok {
+		return err
+	}
+
+	previous := path.mtu
+
+	path.mtu, err = path.search(previous+1, path.ceiling)
+	if err != nil {
+		return err
+	}
+
+	return m.logf(path, "Path MTU to %s grew from %d to %s bytes%s.\n",
+		path.ipaddr, previous, m.colors.Blue.Sprint(path.mtu), path.cause(m.colors))
+}
+
+func (m *PMTU) summary() error {
+	for _, path := range m.paths {
+		var err error
+
+		switch path.lowest {
+		case 0:
+			err = m.out.Printf("%s path MTU to %s: unknown\n", path.family, path.ipaddr)
+		case path.mtu:
+			err = m.out.Printf("%s path MTU to %s: %d bytes\n", path.family, path.ipaddr, path.mtu)
+		default:
+			// A path that went down is no longer known to carry anything
+			mtu := "unknown"
+			if path.mtu != 0 {
+				mtu = fmt.Sprintf("%d bytes", path.mtu)
+			}
+
+			err = m.out.Printf("%s path MTU to %s: %s (lowest %s)\n", path.family, path.ipaddr, mtu, m.colors.Red.Sprint(path.lowest))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func runPMTU(host string) error {
+	err := setTimeZone()
+	if err != nil {
+		return err
+	}
+
+	color.NoColor = !colorize
+
+	m, err := newPMTU(host)
+	if err != nil {
+		return err
+	}
+	defer m.out.Close()
+
+	startedAt = time.Now()
+
+	err = m.logf(nil, "PMTU %s%s.\n", host, globalBinding().banner(m.colors))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range m.paths {
+		err = m.discover(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !watch {
+		return nil
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(pmtuInterval)
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for checks := 0; count == 0 || checks < count; checks++ {
+		select {
+		case <-ticker.C:
+		case <-interrupt:
+			return m.summary()
+		case <-deadline.C:
+			return m.summary()
+		}
+
+		for _, path := range m.paths {
+			err = m.check(path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return m.summary()
+}
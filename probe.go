@@ -0,0 +1,420 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	ping "github.com/prometheus-community/pro-bing"
+)
+
+// Prober is anything that can probe a target once per interval; *ping.Pinger is the ICMP implementation.
+type Prober interface {
+	Run() error
+	Stop()
+	Statistics() *ping.Statistics
+	Addr() string
+	IPAddr() *net.IPAddr
+}
+
+// Reply is a successful probe, along with anything its protocol measured beyond the round trip.
+type Reply struct {
+	*ping.Packet
+	Details []Detail
+}
+
+// Detail is a value logged as key=value; durations are logged in JSON as <key>_ns.
+// Warn marks a value worth drawing attention to, as with replies over --max-rtt.
+type Detail struct {
+	Key   string
+	Value any
+	Warn  bool
+}
+
+// addSpan records the time between start and end, if the probe got that far.
+func (r *Reply) addSpan(key string, start, end time.Time) {
+	if start.IsZero() || end.IsZero() {
+		return
+	}
+
+	r.Details = append(r.Details, Detail{Key: key, Value: end.Sub(start)})
+}
+
+type probeResult struct {
+	pkt     *ping.Packet
+	details []Detail
+	err     error
+}
+
+// RequestProber times a request/response exchange, such as a TCP handshake, once per
+// interval. It reports through the same callbacks as a pro-bing Pinger, so the rest of
+// pinglog need not care which protocol a target uses.
+//
+// Probes that outlive the reply timeout are abandoned, and left for the tracker to
+// declare lost; any other failure is passed to OnFail as soon as it happens.
+type RequestProber struct {
+	Count        int
+	Interval     time.Duration
+	Timeout      time.Duration
+	ReplyTimeout time.Duration
+
+	// Flood sends the next probe as soon as one completes, rather than waiting out the interval
+	Flood bool
+
+	// Preload is the number of probes sent back to back at the start
+	Preload int
+
+	// Burst is the number of probes sent every interval, BurstSpacing apart
+	Burst        int
+	BurstSpacing time.Duration
+
+	OnSend   func(*ping.Packet)
+	OnRecv   func(*Reply)
+	OnFail   func(*ping.Packet, error)
+	OnFinish func(*ping.Statistics)
+
+	probeStats
+
+	probe func(ctx context.Context, ipaddr *net.IPAddr) ([]Detail, error)
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// probeStats keeps the same running statistics as a pro-bing Pinger, for probers that are not one.
+type probeStats struct {
+	addr   string
+	ipaddr *net.IPAddr
+
+	statsMu  sync.Mutex
+	sent     int
+	recv     int
+	dups     int
+	minRtt   time.Duration
+	maxRtt   time.Duration
+	avgRtt   time.Duration
+	stddevm2 float64
+}
+
+func (s *probeStats) Addr() string {
+	return s.addr
+}
+
+func (s *probeStats) IPAddr() *net.IPAddr {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	return s.ipaddr
+}
+
+func (s *probeStats) Statistics() *ping.Statistics {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	var loss float64
+	if s.sent > 0 {
+		loss = float64(s.sent-s.recv) / float64(s.sent) * 100
+	}
+
+	var stdDevRtt time.Duration
+	if s.recv > 0 {
+		stdDevRtt = time.Duration(math.Sqrt(s.stddevm2 / float64(s.recv)))
+	}
+
+	return &ping.Statistics{
+		PacketsSent:           s.sent,
+		PacketsRecv:           s.recv,
+		PacketsRecvDuplicates: s.dups,
+		PacketLoss:            loss,
+		Addr:                  s.addr,
+		IPAddr:                s.ipaddr,
+		MinRtt:                s.minRtt,
+		MaxRtt:                s.maxRtt,
+		AvgRtt:                s.avgRtt,
+		StdDevRtt:             stdDevRtt,
+	}
+}
+
+func (s *probeStats) updateSent() {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	s.sent++
+}
+
+func (s *probeStats) updateDuplicates() {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	s.dups++
+}
+
+func (s *probeStats) updateStatistics(pkt *ping.Packet) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+
+	s.recv++
+
+	if s.recv == 1 || pkt.Rtt < s.minRtt {
+		s.minRtt = pkt.Rtt
+	}
+
+	if pkt.Rtt > s.maxRtt {
+		s.maxRtt = pkt.Rtt
+	}
+
+	// Welford's online method, as used by pro-bing
+	delta := pkt.Rtt - s.avgRtt
+	s.avgRtt += delta / time.Duration(s.recv)
+	delta2 := pkt.Rtt - s.avgRtt
+	s.stddevm2 += float64(delta) * float64(delta2)
+}
+
+// newRequestProber returns a prober that runs probe against target at ipaddr, paced by target's interval and the global flags.
+func newRequestProber(target *Target, ipaddr *net.IPAddr, probe func(ctx context.Context, ipaddr *net.IPAddr) ([]Detail, error)) *RequestProber {
+	return &RequestProber{
+		Count:        probeCount(),
+		Interval:     target.Interval,
+		Timeout:      timeout,
+		ReplyTimeout: replyTimeout,
+		Flood:        flood,
+		Preload:      preload,
+		Burst:        burst,
+		BurstSpacing: burstSpacing,
+		probeStats: probeStats{
+			addr:   target.Host,
+			ipaddr: ipaddr,
+		},
+		probe: probe,
+		done:  make(chan struct{}),
+	}
+}
+
+func (p *RequestProber) Stop() {
+	p.stopOnce.Do(func() {
+		close(p.done)
+	})
+}
+
+func (p *RequestProber) send(ctx context.Context, seq int, results chan<- probeResult) {
+	pkt := &ping.Packet{
+		Addr:   p.addr,
+		IPAddr: p.IPAddr(),
+		// Wrap like ICMP sequence numbers, which is what the tracker expects
+		Seq: seq % int(sequenceSpace),
+	}
+
+	p.updateSent()
+
+	if p.OnSend != nil {
+		p.OnSend(pkt)
+	}
+
+	go func() {
+		probeCtx, cancel := context.WithTimeout(ctx, p.ReplyTimeout)
+		defer cancel()
+
+		start := time.Now()
+
+		details, err := p.probe(probeCtx, pkt.IPAddr)
+
+		pkt.Rtt = time.Since(start)
+
+		select {
+		case results <- probeResult{pkt: pkt, details: details, err: err}:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// Run probes until Count probes have completed, Timeout passes, or Stop is called.
+func (p *RequestProber) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(p.Timeout)
+	defer deadline.Stop()
+
+	results := make(chan probeResult)
+
+	seq, inflight := 0, 0
+
+	// The rest of each burst follows its first probe, BurstSpacing apart
+	spacing := time.NewTimer(p.BurstSpacing)
+	spacing.Stop()
+	defer spacing.Stop()
+
+	remaining := 0
+
+	next := func() {
+		if p.Count != 0 && seq >= p.Count {
+			return
+		}
+
+		p.send(ctx, seq, results)
+		seq++
+		inflight++
+	}
+
+	startBurst := func() {
+		next()
+
+		remaining = p.Burst - 1
+		if remaining > 0 {
+			spacing.Reset(p.BurstSpacing)
+		}
+	}
+
+	for range p.Preload - 1 {
+		next()
+	}
+
+	startBurst()
+
+	for p.Count == 0 || seq < p.Count || inflight > 0 {
+		select {
+		case <-p.done:
+			return p.finish()
+		case <-deadline.C:
+			return p.finish()
+		case <-ticker.C:
+			startBurst()
+		case <-spacing.C:
+			next()
+
+			remaining--
+			if remaining > 0 {
+				spacing.Reset(p.BurstSpacing)
+			}
+		case result := <-results:
+			inflight--
+
+			// In flood mode, the interval is only how long to wait on a probe before sending the next regardless
+			if p.Flood && (p.Count == 0 || seq < p.Count) {
+				next()
+
+				ticker.Reset(p.Interval)
+			}
+
+			switch {
+			case result.err == nil:
+				p.updateStatistics(result.pkt)
+
+				if p.OnRecv != nil {
+					p.OnRecv(&Reply{Packet: result.pkt, Details: result.details})
+				}
+			case timedOut(result.err):
+			default:
+				if p.OnFail != nil {
+					p.OnFail(result.pkt, result.err)
+				}
+			}
+		}
+	}
+
+	return p.finish()
+}
+
+func (p *RequestProber) finish() error {
+	if p.OnFinish != nil {
+		p.OnFinish(p.Statistics())
+	}
+
+	return nil
+}
+
+// timedOut reports whether err means a probe ran out of time, rather than failed outright.
+func timedOut(err error) bool {
+	var netErr net.Error
+
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, context.Canceled) ||
+		errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// failureReason boils an error down to its innermost cause, e.g. "connection refused".
+func failureReason(err error) string {
+	for {
+		inner := errors.Unwrap(err)
+		if inner == nil {
+			return err.Error()
+		}
+
+		err = inner
+	}
+}
+
+// ipNetwork returns the network to resolve hosts on, according to --ipv4 and --ipv6.
+func ipNetwork() string {
+	switch {
+	case ipv4:
+		return "ip4"
+	case ipv6:
+		return "ip6"
+	default:
+		return "ip"
+	}
+}
+
+// rttStats keeps running statistics in the style of mtr: loss, and the last, best,
+// worst and average round-trip times, along with their jitter.
+type rttStats struct {
+	sent   int
+	recv   int
+	last   time.Duration
+	best   time.Duration
+	worst  time.Duration
+	total  time.Duration
+	jitter time.Duration
+}
+
+func (s *rttStats) record(rtt time.Duration) {
+	if s.recv > 0 {
+		delta := rtt - s.last
+		if delta < 0 {
+			delta = -delta
+		}
+
+		// Running mean of the difference between consecutive replies
+		s.jitter += (delta - s.jitter) / time.Duration(s.recv)
+	}
+
+	s.recv++
+	s.total += rtt
+
+	if s.recv == 1 || rtt < s.best {
+		s.best = rtt
+	}
+
+	if rtt > s.worst {
+		s.worst = rtt
+	}
+
+	s.last = rtt
+}
+
+func (s *rttStats) loss() float64 {
+	if s.sent == 0 {
+		return 0
+	}
+
+	return float64(s.sent-s.recv) / float64(s.sent) * 100
+}
+
+func (s *rttStats) avg() time.Duration {
+	if s.recv == 0 {
+		return 0
+	}
+
+	return s.total / time.Duration(s.recv)
+}
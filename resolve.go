@@ -0,0 +1,174 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	// How long a single lookup may take
+	resolveTimeout time.Duration = 5 * time.Second
+
+	// How long --wait-for-dns waits before its first retry, and at most between any two
+	resolveBackoff    time.Duration = time.Second
+	resolveMaxBackoff time.Duration = time.Minute
+)
+
+// lookupIPs returns every address host resolves to on network, within resolveTimeout.
+func lookupIPs(host, network string) ([]net.IP, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("lookup %s: no addresses found", host)
+	}
+
+	return ips, nil
+}
+
+// preferredIP picks an address the way net.ResolveIPAddr does, i.e. IPv4 first unless resolving on ip6.
+func preferredIP(ips []net.IP, network string) net.IP {
+	if network != "ip6" {
+		for _, ip := range ips {
+			if ip.To4() != nil {
+				return ip
+			}
+		}
+	}
+
+	return ips[0]
+}
+
+// waitForDNS calls resolve until it succeeds, backing off between attempts, if --wait-for-dns was given;
+// otherwise it just calls it once.
+func waitForDNS(host string, resolve func() error) error {
+	backoff := resolveBackoff
+
+	for {
+		err := resolve()
+		if err == nil || !waitDNS {
+			return err
+		}
+
+		fmt.Fprintf(os.Stderr, "Failed to resolve %s: %s; retrying in %s\n", host, failureReason(err), backoff)
+
+		time.Sleep(backoff)
+
+		backoff = min(backoff*2, resolveMaxBackoff)
+	}
+}
+
+// resolveHost resolves host to a single address on network, retrying according to --wait-for-dns.
+func resolveHost(host, network string) (*net.IPAddr, error) {
+	ip := net.ParseIP(host)
+	if ip != nil {
+		return &net.IPAddr{IP: ip}, nil
+	}
+
+	var ipaddr *net.IPAddr
+
+	err := waitForDNS(host, func() error {
+		ips, err := lookupIPs(host, network)
+		if err != nil {
+			return err
+		}
+
+		ipaddr = &net.IPAddr{IP: preferredIP(ips, network)}
+
+		return nil
+	})
+
+	return ipaddr, err
+}
+
+// AddressWatch keeps track of the address a target is probed at, and with --resolve-every,
+// re-resolves its host periodically, noting when the current address drops out of the answer.
+type AddressWatch struct {
+	mu      sync.Mutex
+	current *net.IPAddr
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newAddressWatch(ipaddr *net.IPAddr) *AddressWatch {
+	return &AddressWatch{
+		current: ipaddr,
+		done:    make(chan struct{}),
+	}
+}
+
+// Current returns the address probes are being sent to.
+func (w *AddressWatch) Current() *net.IPAddr {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.current
+}
+
+func (w *AddressWatch) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.done)
+	})
+}
+
+// Run re-resolves target's host every --resolve-every until stopped. Probers are not moved to a new
+// address while running, so when the current one drops out of the answer, restart is called to
+// replace the target with one that resolves its host afresh.
+func (w *AddressWatch) Run(target *Target, report func(error), restart func()) {
+	// Targets pinned to one of several addresses by --all-addresses stay there, and compared variants
+	// stay in step with each other by keeping their address too
+	if resolveEvery == 0 || target.Address != "" || target.comparison != nil || net.ParseIP(target.Host) != nil {
+		return
+	}
+
+	ticker := time.NewTicker(resolveEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		latest := w.Current()
+
+		// Hosts with addresses in both families should not flap between them
+		network := "ip6"
+		if latest.IP.To4() != nil {
+			network = "ip4"
+		}
+
+		ips, err := lookupIPs(target.Host, network)
+		if err != nil {
+			report(showResolveFailed(err, target))
+
+			continue
+		}
+
+		if slices.ContainsFunc(ips, latest.IP.Equal) {
+			continue
+		}
+
+		report(showAddressChanged(latest, &net.IPAddr{IP: preferredIP(ips, network)}, target))
+
+		restart()
+
+		return
+	}
+}
@@ -0,0 +1,313 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	ping "github.com/prometheus-community/pro-bing"
+)
+
+// Session runs a changing set of targets side by side, and collects them for the final summary.
+type Session struct {
+	mu          sync.Mutex
+	targets     []*Target
+	running     map[string]*Target
+	replacing   map[*Target]bool
+	shared      *Output
+	labelled    bool
+	reopen      bool
+	interrupted bool
+	errors      chan error
+	done        chan *Target
+}
+
+func newSession(labelled bool) *Session {
+	return &Session{
+		running:   make(map[string]*Target),
+		replacing: make(map[*Target]bool),
+		labelled:  labelled,
+		errors:    make(chan error),
+		done:      make(chan *Target),
+	}
+}
+
+func (s *Session) report(err error) {
+	if err != nil {
+		s.errors <- err
+	}
+}
+
+func (s *Session) openOutput(target *Target) (*Output, error) {
+	switch {
+	case output == defaultLogFile:
+		// Each target gets its own <name>.log
+		return newOutput(target.Name, s.reopen)
+	case s.shared == nil:
+		out, err := newOutput(target.Name, s.reopen)
+		if err != nil {
+			return nil, err
+		}
+
+		s.shared = out
+
+		return out, nil
+	default:
+		return s.shared, nil
+	}
+}
+
+// Start resolves and configures target, then begins probing it in the background.
+func (s *Session) Start(target *Target) error {
+	// Resolving can take a while, and with --wait-for-dns forever, so is done before taking the lock
+	prober, err := newProber(target)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// An interrupt that came in while resolving has already stopped everything else
+	if s.interrupted {
+		prober.Stop()
+
+		return nil
+	}
+
+	target.prober = prober
+	target.finished = make(chan struct{})
+	target.address = newAddressWatch(prober.IPAddr())
+	target.colors = newColors(len(s.targets))
+
+	if s.labelled {
+		target.Label = target.Name
+	}
+
+	target.out, err = s.openOutput(target)
+	if err != nil {
+		return err
+	}
+
+	if burst > 1 {
+		target.bursts = newBursts(burst)
+	}
+
+	target.tracker = newTracker(replyTimeout, reorderWindow, func(seq uint64, reason string) {
+		s.report(showLost(seq, reason, target))
+		s.report(settleBurst(seq, 0, true, target))
+
+		if target.comparison != nil {
+			s.report(target.comparison.settle(target, seq, 0, true))
+		}
+
+		stopWhenSettled(target)
+	})
+
+	onSend := func(pkt *ping.Packet) {
+		target.tracker.Sent(pkt.Seq)
+
+		if target.meter != nil {
+			target.meter.Sent()
+		}
+	}
+
+	onRecv := func(reply *Reply) {
+		if target.meter != nil {
+			target.meter.Received()
+		}
+
+		s.report(showReceived(reply, target))
+	}
+
+	onFail := func(pkt *ping.Packet, err error) {
+		target.tracker.Fail(pkt.Seq, failureReason(err))
+	}
+
+	onFinish := func(stats *ping.Statistics) {
+		target.address.Stop()
+
+		if target.meter != nil {
+			target.meter.Stop()
+		}
+
+		// At flood rates there is always something in flight when time runs out, which was cut off rather than lost,
+		// as is anything in flight when a target is restarted
+		if s.Interrupted() || flood || s.restarting(target) {
+			target.tracker.Stop()
+		} else {
+			target.tracker.Flush()
+		}
+
+		s.report(showFinalStatistics(stats, target, s.labelled))
+
+		// Shared outputs are closed once the whole session is over
+		if target.out != s.shared {
+			s.report(target.out.Close())
+		}
+
+		close(target.finished)
+
+		// A target being restarted is accounted for by its replacement instead
+		if !s.restarting(target) {
+			s.done <- target
+		}
+	}
+
+	switch p := prober.(type) {
+	case *ping.Pinger:
+		p.OnSend = onSend
+		p.OnRecv = func(pkt *ping.Packet) {
+			onRecv(&Reply{Packet: pkt})
+		}
+		p.OnDuplicateRecv = func(pkt *ping.Packet) {
+			s.report(showDuplicate(pkt, target))
+		}
+		p.OnFinish = onFinish
+	case *RequestProber:
+		p.OnSend = onSend
+		p.OnRecv = onRecv
+		p.OnFail = onFail
+		p.OnFinish = onFinish
+	case *EchoProber:
+		p.OnSend = onSend
+		p.OnRecv = onRecv
+		p.OnDuplicateRecv = func(pkt *ping.Packet) {
+			s.report(showDuplicate(pkt, target))
+		}
+		p.OnFail = onFail
+		p.OnFinish = onFinish
+	case *HTTPProber:
+		p.OnSend = onSend
+		p.OnRecv = onRecv
+		p.OnFail = onFail
+		p.OnFinish = onFinish
+	}
+
+	err = showStart(target)
+	if err != nil {
+		return err
+	}
+
+	s.targets = append(s.targets, target)
+	s.running[target.Name] = target
+
+	target.startTime = time.Now()
+
+	go target.address.Run(target, s.report, func() {
+		s.report(s.Restart(target, target.fresh()))
+	})
+
+	if flood {
+		target.meter = newFloodMeter(s.labelled)
+
+		go target.meter.Run(target, s.report)
+	}
+
+	go func() {
+		s.report(prober.Run())
+	}()
+
+	return nil
+}
+
+// Restart stops target and starts replacement in its place, once target has finished with its log file.
+func (s *Session) Restart(target, replacement *Target) error {
+	s.mu.Lock()
+	s.replacing[target] = true
+	s.mu.Unlock()
+
+	target.prober.Stop()
+
+	<-target.finished
+
+	err := s.Start(replacement)
+
+	// Without a replacement running, target is finished after all
+	if err != nil || s.Interrupted() {
+		s.done <- target
+	}
+
+	return err
+}
+
+func (s *Session) restarting(target *Target) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.replacing[target]
+}
+
+// Finished removes target from the running set, and reports how many targets are still running.
+func (s *Session) Finished(target *Target) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running[target.Name] == target {
+		delete(s.running, target.Name)
+	}
+
+	return len(s.running)
+}
+
+func (s *Session) Running() map[string]*Target {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	running := make(map[string]*Target, len(s.running))
+
+	for name, target := range s.running {
+		running[name] = target
+	}
+
+	return running
+}
+
+// Targets returns every target started during the session, including those since removed.
+func (s *Session) Targets() []*Target {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*Target(nil), s.targets...)
+}
+
+// Reopen makes targets started from now on append to existing log files, rather
+// than prompting to overwrite them in the middle of a run.
+func (s *Session) Reopen() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reopen = true
+}
+
+func (s *Session) Interrupt() {
+	s.mu.Lock()
+	s.interrupted = true
+	s.mu.Unlock()
+
+	s.StopAll()
+}
+
+func (s *Session) Interrupted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.interrupted
+}
+
+func (s *Session) StopAll() {
+	for _, target := range s.Running() {
+		target.prober.Stop()
+	}
+}
+
+func (s *Session) Close() error {
+	if s.shared == nil {
+		return nil
+	}
+
+	return s.shared.Close()
+}
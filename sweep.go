@@ -0,0 +1,640 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"golang.org/x/net/icmp"
+	netipv4 "golang.org/x/net/ipv4"
+	netipv6 "golang.org/x/net/ipv6"
+)
+
+const (
+	// Pings sent to each address before deciding it is down
+	sweepAttempts int = 2
+
+	sweepSpacing time.Duration = 250 * time.Millisecond
+
+	// Ranges with more host bits than this are more likely a typo than a network anyone means to sweep
+	maxSweepBits int = 16
+)
+
+// sweepReply is the first reply an address gave during a sweep.
+type sweepReply struct {
+	addr netip.Addr
+	rtt  time.Duration
+	ttl  int
+}
+
+// sweepAddresses returns every address in a range given as an address or a CIDR prefix,
+// leaving out the network and broadcast addresses of IPv4 subnets, as fping -g does.
+func sweepAddresses(value string) ([]netip.Addr, error) {
+	addr, err := netip.ParseAddr(value)
+	if err == nil {
+		return []netip.Addr{addr}, nil
+	}
+
+	prefix, err := netip.ParsePrefix(value)
+	if err != nil {
+		return nil, ErrInvalidRange
+	}
+
+	prefix = prefix.Masked()
+
+	if prefix.Addr().BitLen()-prefix.Bits() > maxSweepBits {
+		return nil, ErrRangeTooLarge
+	}
+
+	var addrs []netip.Addr
+
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		addrs = append(addrs, addr)
+	}
+
+	if prefix.Addr().Is4() && prefix.Bits() <= 30 {
+		addrs = addrs[1 : len(addrs)-1]
+	}
+
+	return addrs, nil
+}
+
+// A probe of one address, waiting on its replies
+type sweepProbe struct {
+	sent    map[int]time.Time
+	replies chan *sweepReply
+}
+
+// sweepSocket sends the echo requests for every address in a sweep over a single ICMP socket,
+// and hands each reply to the probe of the address it came from. A raw socket sees every reply
+// the host receives, so a socket per address would have each sift through all the others' too.
+type sweepSocket struct {
+	conn       *icmp.PacketConn
+	v6         bool
+	privileged bool
+	id         int
+	ifIndex    int
+
+	mu      sync.Mutex
+	seq     int
+	waiting map[netip.Addr]*sweepProbe
+}
+
+// newSweepSocket opens the socket for sweeping a range of IPv4 (or IPv6) addresses, failing
+// if ICMP sockets cannot be opened at all, before any address is probed.
+func newSweepSocket(v6 bool) (*sweepSocket, error) {
+	privileged, err := icmpPrivileged(v6)
+	if err != nil {
+		return nil, err
+	}
+
+	network, address := "udp4", "0.0.0.0"
+	if privileged {
+		network = "ip4:icmp"
+	}
+
+	if v6 {
+		network, address = "udp6", "::"
+		if privileged {
+			network = "ip6:ipv6-icmp"
+		}
+	}
+
+	if sourceAddress() != "" {
+		address = sourceAddress()
+	}
+
+	conn, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &sweepSocket{
+		conn:       conn,
+		v6:         v6,
+		privileged: privileged,
+		id:         rand.IntN(1 << 16),
+		waiting:    make(map[netip.Addr]*sweepProbe),
+	}
+
+	err = s.configure()
+	if err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	go s.receive()
+
+	return s, nil
+}
+
+// configure applies --ttl, --interface and any traffic class to the socket. As with
+// EchoProber, platforms that cannot report the TTL of replies just show them without one.
+func (s *sweepSocket) configure() error {
+	if interfaceName() != "" {
+		iface, err := net.InterfaceByName(interfaceName())
+		if err != nil {
+			return err
+		}
+
+		s.ifIndex = iface.Index
+	}
+
+	if s.v6 {
+		p := s.conn.IPv6PacketConn()
+
+		_ = p.SetControlMessage(netipv6.FlagHopLimit, true)
+
+		if trafficClass() != 0 {
+			err := p.SetTrafficClass(trafficClass())
+			if err != nil {
+				return err
+			}
+		}
+
+		return p.SetHopLimit(ttl)
+	}
+
+	p := s.conn.IPv4PacketConn()
+
+	_ = p.SetControlMessage(netipv4.FlagTTL, true)
+
+	if trafficClass() != 0 {
+		err := p.SetTOS(trafficClass())
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.SetTTL(ttl)
+}
+
+func (s *sweepSocket) Close() error {
+	return s.conn.Close()
+}
+
+// send sends one echo request to addr, on behalf of probe.
+func (s *sweepSocket) send(addr netip.Addr, probe *sweepProbe) error {
+	var echo icmp.Type = netipv4.ICMPTypeEcho
+	if s.v6 {
+		echo = netipv6.ICMPTypeEchoRequest
+	}
+
+	s.mu.Lock()
+	seq := s.seq
+	s.seq = (s.seq + 1) % int(sequenceSpace)
+	probe.sent[seq] = time.Now()
+	s.mu.Unlock()
+
+	msg := icmp.Message{
+		Type: echo,
+		Body: &icmp.Echo{
+			ID:   s.id,
+			Seq:  seq,
+			Data: make([]byte, size),
+		},
+	}
+
+	packet, err := msg.Marshal(nil)
+	if err != nil {
+		return err
+	}
+
+	// Datagram sockets take a UDP address, even though no UDP is involved
+	var dst net.Addr = &net.IPAddr{IP: addr.AsSlice()}
+	if !s.privileged {
+		dst = &net.UDPAddr{IP: addr.AsSlice()}
+	}
+
+	if s.v6 {
+		var cm *netipv6.ControlMessage
+		if s.ifIndex != 0 {
+			cm = &netipv6.ControlMessage{IfIndex: s.ifIndex}
+		}
+
+		_, err = s.conn.IPv6PacketConn().WriteTo(packet, cm, dst)
+
+		return err
+	}
+
+	var cm *netipv4.ControlMessage
+	if s.ifIndex != 0 {
+		cm = &netipv4.ControlMessage{IfIndex: s.ifIndex}
+	}
+
+	_, err = s.conn.IPv4PacketConn().WriteTo(packet, cm, dst)
+
+	return err
+}
+
+// receive hands each echo reply to the probe waiting on the address it came from, until the socket is closed.
+func (s *sweepSocket) receive() {
+	proto := 1
+	if s.v6 {
+		proto = 58
+	}
+
+	buf := make([]byte, 65536)
+
+	for {
+		var n, replyTTL int
+		var src net.Addr
+		var err error
+
+		if s.v6 {
+			var cm *netipv6.ControlMessage
+
+			n, cm, src, err = s.conn.IPv6PacketConn().ReadFrom(buf)
+			if cm != nil {
+				replyTTL = cm.HopLimit
+			}
+		} else {
+			var cm *netipv4.ControlMessage
+
+			n, cm, src, err = s.conn.IPv4PacketConn().ReadFrom(buf)
+			if cm != nil {
+				replyTTL = cm.TTL
+			}
+		}
+		if err != nil {
+			return
+		}
+
+		received := time.Now()
+
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil || (msg.Type != netipv4.ICMPTypeEchoReply && msg.Type != netipv6.ICMPTypeEchoReply) {
+			continue
+		}
+
+		// Datagram sockets only ever see their own replies, with an ID the kernel chose
+		body, ok := msg.Body.(*icmp.Echo)
+		if !ok || (s.privileged && body.ID != s.id) {
+			continue
+		}
+
+		var ip net.IP
+
+		switch src := src.(type) {
+		case *net.IPAddr:
+			ip = src.IP
+		case *net.UDPAddr:
+			ip = src.IP
+		}
+
+		addr, ok := netip.AddrFromSlice(ip)
+		if !ok {
+			continue
+		}
+
+		addr = addr.Unmap()
+
+		s.mu.Lock()
+
+		probe := s.waiting[addr]
+
+		var sent time.Time
+		if probe != nil {
+			sent, ok = probe.sent[body.Seq]
+		}
+
+		s.mu.Unlock()
+
+		if probe == nil || !ok {
+			continue
+		}
+
+		// Only the first reply is wanted, so any after it are dropped
+		select {
+		case probe.replies <- &sweepReply{addr: addr, rtt: received.Sub(sent), ttl: replyTTL}:
+		default:
+		}
+	}
+}
+
+// probe pings addr until it replies or runs out of attempts, returning nil if it never did.
+func (s *sweepSocket) probe(addr netip.Addr) *sweepReply {
+	probe := &sweepProbe{
+		sent:    make(map[int]time.Time),
+		replies: make(chan *sweepReply, 1),
+	}
+
+	s.mu.Lock()
+	s.waiting[addr] = probe
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.waiting, addr)
+		s.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(sweepSpacing)
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(time.Duration(sweepAttempts-1)*sweepSpacing + sweepReplyTimeout)
+	defer deadline.Stop()
+
+	// Failing to send to one address, e.g. for want of a route, just means it is down
+	err := s.send(addr, probe)
+	if err != nil {
+		return nil
+	}
+
+	for sent := 1; ; {
+		select {
+		case reply := <-probe.replies:
+			return reply
+		case <-deadline.C:
+			return nil
+		case <-ticker.C:
+			if sent == sweepAttempts {
+				continue
+			}
+
+			err := s.send(addr, probe)
+			if err != nil {
+				return nil
+			}
+
+			sent++
+		}
+	}
+}
+
+// Sweep pings every address in a range, a limited number at a time, and keeps track of which are up.
+type Sweep struct {
+	value  string
+	addrs  []netip.Addr
+	up     map[netip.Addr]*sweepReply
+	socket *sweepSocket
+	out    *Output
+	colors *Colors
+}
+
+func newSweep(value string) (*Sweep, error) {
+	addrs, err := sweepAddresses(value)
+	if err != nil {
+		return nil, err
+	}
+
+	socket, err := newSweepSocket(addrs[0].Is6())
+	if err != nil {
+		return nil, err
+	}
+
+	// Prefixes would otherwise put the log file in a subdirectory
+	out, err := newOutput(strings.ReplaceAll(value, "/", "_"), false)
+	if err != nil {
+		socket.Close()
+
+		return nil, err
+	}
+
+	return &Sweep{
+		value:  value,
+		addrs:  addrs,
+		socket: socket,
+		out:    out,
+		colors: newColors(0),
+	}, nil
+}
+
+// run sweeps the range once, returning the addresses that replied, or nil if stopped part way through.
+func (s *Sweep) run(stop <-chan struct{}) map[netip.Addr]*sweepReply {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	up := make(map[netip.Addr]*sweepReply)
+
+	limit := make(chan struct{}, concurrency)
+
+	for _, addr := range s.addrs {
+		select {
+		case limit <- struct{}{}:
+		case <-stop:
+			wg.Wait()
+
+			return nil
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-limit }()
+
+			reply := s.socket.probe(addr)
+			if reply == nil {
+				return
+			}
+
+			mu.Lock()
+			up[addr] = reply
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	return up
+}
+
+func (s *Sweep) prefix() string {
+	if timestamp {
+		return s.colors.Grey.Sprint(formatTimestamp(time.Now())) + " | "
+	}
+
+	return ""
+}
+
+func (s *Sweep) showStart() error {
+	switch format {
+	case "json":
+		return nil
+	case "csv":
+		return s.out.Record(csvSweepHeader)
+	}
+
+	return s.out.Printf("SWEEP %s (%s addresses)%s.\n",
+		s.colors.Green.Sprint(s.value),
+		s.colors.Blue.Sprint(len(s.addrs)),
+		globalBinding().banner(s.colors))
+}
+
+func (s *Sweep) showUp(reply *sweepReply) error {
+	switch format {
+	case "json":
+		return s.out.Event(newJSONSweepHost("up", reply.addr, reply, s.value))
+	case "csv":
+		return s.out.Record(newCSVSweepHost("up", reply.addr, reply, s.value))
+	}
+
+	return s.out.Printf("%s%s is up: ttl=%s time=%s\n",
+		s.prefix(),
+		s.colors.Green.Sprint(reply.addr),
+		s.colors.Blue.Sprint(reply.ttl),
+		s.colors.Blue.Sprint(reply.rtt.Round(time.Microsecond)))
+}
+
+func (s *Sweep) showDown(addr netip.Addr) error {
+	switch format {
+	case "json":
+		return s.out.Event(newJSONSweepHost("down", addr, nil, s.value))
+	case "csv":
+		return s.out.Record(newCSVSweepHost("down", addr, nil, s.value))
+	}
+
+	return s.out.Printf("%s%s\n", s.prefix(), s.colors.Red.Sprintf("%s is down.", addr))
+}
+
+// showSummary notes how many addresses were up in the latest sweep; CSV output has no row for it.
+func (s *Sweep) showSummary(up int, elapsed time.Duration) error {
+	switch format {
+	case "json":
+		return s.out.Event(newJSONSweepSummary(up, len(s.addrs), elapsed, s.value))
+	case "csv":
+		return nil
+	}
+
+	return s.out.Printf("%s%s of %s addresses answered, in %s.\n",
+		s.prefix(),
+		s.colors.Blue.Sprint(up),
+		s.colors.Blue.Sprint(len(s.addrs)),
+		s.colors.Blue.Sprint(elapsed.Round(time.Millisecond)))
+}
+
+// compare logs every address that has come up or gone down since the previous sweep, in address order.
+func (s *Sweep) compare(up map[netip.Addr]*sweepReply) error {
+	var changed []netip.Addr
+
+	for addr := range up {
+		if s.up[addr] == nil {
+			changed = append(changed, addr)
+		}
+	}
+
+	for addr := range s.up {
+		if up[addr] == nil {
+			changed = append(changed, addr)
+		}
+	}
+
+	slices.SortFunc(changed, netip.Addr.Compare)
+
+	for _, addr := range changed {
+		var err error
+
+		reply := up[addr]
+		if reply != nil {
+			err = s.showUp(reply)
+		} else {
+			err = s.showDown(addr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	s.up = up
+
+	return nil
+}
+
+func runSweep(value string) error {
+	err := setTimeZone()
+	if err != nil {
+		return err
+	}
+
+	color.NoColor = !colorize
+
+	s, err := newSweep(value)
+	if err != nil {
+		return err
+	}
+	defer s.out.Close()
+	defer s.socket.Close()
+
+	startedAt = time.Now()
+
+	err = s.showStart()
+	if err != nil {
+		return err
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	stop := make(chan struct{})
+
+	go func() {
+		select {
+		case <-interrupt:
+		case <-deadline.C:
+		}
+
+		close(stop)
+	}()
+
+	var took time.Duration
+
+	for sweeps := 0; count == 0 || sweeps < count; sweeps++ {
+		started := time.Now()
+
+		up := s.run(stop)
+
+		// A partial sweep would make every address it did not reach look down
+		if up == nil {
+			break
+		}
+
+		took = time.Since(started)
+
+		err = s.compare(up)
+		if err != nil {
+			return err
+		}
+
+		if !watch {
+			return s.showSummary(len(up), took)
+		}
+
+		// With --watch, only changes are logged after the first sweep
+		if sweeps == 0 {
+			err = s.showSummary(len(up), took)
+			if err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-time.After(time.Until(started.Add(sweepInterval))):
+		case <-stop:
+			return s.showSummary(len(s.up), took)
+		}
+	}
+
+	// Stopped before the first sweep was done, so there is nothing to sum up
+	if s.up == nil {
+		return nil
+	}
+
+	return s.showSummary(len(s.up), took)
+}
@@ -0,0 +1,445 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	ping "github.com/prometheus-community/pro-bing"
+)
+
+const (
+	ProtocolICMP string = "icmp"
+	ProtocolTCP  string = "tcp"
+	ProtocolTLS  string = "tls"
+	ProtocolHTTP string = "http"
+	ProtocolDNS  string = "dns"
+)
+
+type Target struct {
+	Host         string
+	Name         string
+	Label        string
+	Protocol     string
+	Network      string
+	Address      string
+	Port         int
+	URL          string
+	ExpectStatus int
+	ExpectBody   string
+	QueryName    string
+	QueryType    string
+	ServerName   string
+	Binding
+	Variant    string
+	Interval   time.Duration
+	MaxRtt     time.Duration
+	Size       int
+	prober     Prober
+	address    *AddressWatch
+	meter      *FloodMeter
+	bursts     *Bursts
+	comparison *Comparison
+	variant    int
+	fromFile   bool
+	tracker    *Tracker
+	colors     *Colors
+	out        *Output
+	startTime  time.Time
+	finished   chan struct{}
+}
+
+// Matches [scheme://]host[:port][/anything], where host may be a bracketed IPv6 address
+var hostUrl = regexp.MustCompile(`^(?:([^:\/]+)://)?(\[[^\]]+\]|[^:\/]+)(?::(\d+))?(.+)?$`)
+
+// parseHost splits argument into its scheme, host and port, any of which may be empty.
+func parseHost(argument string) (string, string, string) {
+	if net.ParseIP(argument) != nil {
+		return "", argument, ""
+	}
+
+	asUrl := hostUrl.FindStringSubmatch(argument)
+	if asUrl == nil {
+		return "", "", ""
+	}
+
+	return asUrl[1], strings.Trim(asUrl[2], "[]"), asUrl[3]
+}
+
+func parsePort(value string) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return 0, ErrInvalidPort
+	}
+
+	return port, nil
+}
+
+// newTarget returns a target for argument, using the global flags for every per-target setting.
+func newTarget(argument string) (*Target, error) {
+	scheme, host, port := parseHost(argument)
+
+	target := &Target{
+		Host:     host,
+		Name:     host,
+		Protocol: ProtocolICMP,
+		Network:  ipNetwork(),
+		Interval: interval,
+		MaxRtt:   maxRtt,
+		Size:     size,
+		Binding:  globalBinding(),
+	}
+
+	switch {
+	case scheme == "http" || scheme == "https":
+		target.Protocol = ProtocolHTTP
+		target.URL = argument
+		target.ExpectStatus = expectStatus
+		target.ExpectBody = expectBody
+
+		target.Port = 80
+		if scheme == "https" {
+			target.Port = 443
+		}
+	case scheme == ProtocolTLS:
+		target.Protocol = ProtocolTLS
+		target.Port = 443
+		target.ServerName = sni
+	case scheme == ProtocolDNS:
+		target.Protocol = ProtocolDNS
+		target.Port = 53
+		target.QueryName = dnsName
+		target.QueryType = dnsType
+	case scheme == ProtocolTCP && port == "" && tcpPort == 0:
+		return nil, fmt.Errorf("%s: no port given for tcp target", argument)
+	case scheme == ProtocolTCP || tcpPort != 0:
+		target.Protocol = ProtocolTCP
+		target.Port = tcpPort
+	}
+
+	// A port in the argument overrides --tcp and the scheme's default; ICMP targets have no use for one
+	if target.Protocol != ProtocolICMP && port != "" {
+		var err error
+
+		target.Port, err = parsePort(port)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", argument, err)
+		}
+	}
+
+	return target, nil
+}
+
+func newProber(target *Target) (Prober, error) {
+	switch target.Protocol {
+	case ProtocolTCP:
+		return newTCPProber(target)
+	case ProtocolHTTP:
+		return newHTTPProber(target)
+	case ProtocolDNS:
+		return newDNSProber(target)
+	case ProtocolTLS:
+		return newTLSProber(target)
+	default:
+		// A pro-bing Pinger can only send once per interval
+		if flood || preload > 0 || burst > 1 {
+			return newEchoProber(target)
+		}
+
+		return newPinger(target)
+	}
+}
+
+func newPinger(target *Target) (*ping.Pinger, error) {
+	pinger := ping.New(target.Host)
+
+	err := configurePinger(pinger, target)
+	if err != nil {
+		return nil, err
+	}
+
+	return pinger, nil
+}
+
+// resolve returns the address target should be probed at: the one --all-addresses pinned it to, if any,
+// or else whatever its host resolves to.
+func (t *Target) resolve() (*net.IPAddr, error) {
+	if t.Address != "" {
+		return &net.IPAddr{IP: net.ParseIP(t.Address)}, nil
+	}
+
+	return resolveHost(t.Host, t.Network)
+}
+
+// fresh returns a copy of t's settings, to be started in its place.
+func (t *Target) fresh() *Target {
+	return &Target{
+		Host:         t.Host,
+		Name:         t.Name,
+		Protocol:     t.Protocol,
+		Network:      t.Network,
+		Address:      t.Address,
+		Port:         t.Port,
+		URL:          t.URL,
+		ExpectStatus: t.ExpectStatus,
+		ExpectBody:   t.ExpectBody,
+		QueryName:    t.QueryName,
+		QueryType:    t.QueryType,
+		ServerName:   t.ServerName,
+		Binding:      t.Binding,
+		Variant:      t.Variant,
+		Interval:     t.Interval,
+		MaxRtt:       t.MaxRtt,
+		Size:         t.Size,
+		comparison:   t.comparison,
+		variant:      t.variant,
+		fromFile:     t.fromFile,
+	}
+}
+
+// sameSettings reports whether two targets would ping in exactly the same way.
+func sameSettings(a, b *Target) bool {
+	return a.Host == b.Host &&
+		a.Name == b.Name &&
+		a.Protocol == b.Protocol &&
+		a.Network == b.Network &&
+		a.Address == b.Address &&
+		a.Port == b.Port &&
+		a.URL == b.URL &&
+		a.ExpectStatus == b.ExpectStatus &&
+		a.ExpectBody == b.ExpectBody &&
+		a.QueryName == b.QueryName &&
+		a.QueryType == b.QueryType &&
+		a.ServerName == b.ServerName &&
+		a.Binding == b.Binding &&
+		a.Variant == b.Variant &&
+		a.Interval == b.Interval &&
+		a.MaxRtt == b.MaxRtt &&
+		a.Size == b.Size
+}
+
+func setTargetOption(target *Target, key, value string) error {
+	var err error
+
+	switch key {
+	case "name":
+		if value == "" {
+			return fmt.Errorf("name must not be empty")
+		}
+
+		target.Name = value
+	case "interval":
+		target.Interval, err = time.ParseDuration(value)
+
+		switch {
+		case err != nil:
+		case target.Interval <= 0:
+			err = ErrInvalidInterval
+		case burst > 1 && time.Duration(burst-1)*burstSpacing >= target.Interval:
+			err = ErrBurstTooLong
+		}
+	case "max-rtt":
+		target.MaxRtt, err = time.ParseDuration(value)
+	case "size":
+		target.Size, err = strconv.Atoi(value)
+		if err == nil && (target.Size < 1 || target.Size > 65527) {
+			err = ErrInvalidSize
+		}
+	case "tcp":
+		target.Protocol = ProtocolTCP
+		target.Port, err = parsePort(value)
+	case "expect-status":
+		target.ExpectStatus, err = strconv.Atoi(value)
+		if err == nil && !validExpectedStatus(target.ExpectStatus) {
+			err = ErrInvalidStatus
+		}
+	case "expect-body":
+		target.ExpectBody = value
+	case "sni":
+		target.ServerName = value
+	case "dns-name":
+		target.QueryName = value
+	case "dns-type":
+		target.QueryType = value
+		if !validDNSType(value) {
+			err = ErrInvalidDNSType
+		}
+	default:
+		err = fmt.Errorf("unknown option %q", key)
+	}
+
+	return err
+}
+
+// parseTargetLine reads a line of the form "<host> [key=value]...".
+func parseTargetLine(line string) (*Target, error) {
+	fields := strings.Fields(line)
+
+	target, err := newTarget(fields[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range fields[1:] {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			return nil, fmt.Errorf("expected key=value, found %q", field)
+		}
+
+		err := setTargetOption(target, key, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return target, nil
+}
+
+// readTargetsFile parses a file with one target per line; blank lines and lines starting with # are ignored.
+func readTargetsFile(path string) ([]*Target, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var targets []*Target
+
+	names := make(map[string]struct{})
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		target, err := parseTargetLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+
+		_, duplicate := names[target.Name]
+		if duplicate {
+			return nil, fmt.Errorf("%s:%d: duplicate target name %q", path, line, target.Name)
+		}
+
+		names[target.Name] = struct{}{}
+
+		target.fromFile = true
+
+		targets = append(targets, target)
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return expandVariants(targets)
+}
+
+// checkDuplicateNames rejects targets that share a name, as targets are told apart by name in
+// labels, log file names and summaries, and in the set of those running.
+func checkDuplicateNames(targets []*Target) error {
+	names := make(map[string]struct{}, len(targets))
+
+	for _, target := range targets {
+		_, duplicate := names[target.Name]
+		if duplicate {
+			return fmt.Errorf("duplicate target name %q; use name= in the targets file to tell them apart", target.Name)
+		}
+
+		names[target.Name] = struct{}{}
+	}
+
+	return nil
+}
+
+// reloadTargets re-reads the targets file, stopping targets that were removed from it,
+// restarting those whose options changed, and starting those that were added.
+func reloadTargets(session *Session) error {
+	targets, err := readTargetsFile(targetsFile)
+	if err != nil {
+		return err
+	}
+
+	// Targets given on the command line keep running whatever the file says, so still hold their names,
+	// once each however many times they have been restarted
+	var arguments []*Target
+
+	seen := make(map[string]bool)
+
+	for _, target := range session.Targets() {
+		if !target.fromFile && !seen[target.Name] {
+			seen[target.Name] = true
+
+			arguments = append(arguments, target)
+		}
+	}
+
+	err = checkDuplicateNames(append(arguments, targets...))
+	if err != nil {
+		return err
+	}
+
+	wanted := make(map[string]*Target, len(targets))
+
+	for _, target := range targets {
+		wanted[target.Name] = target
+	}
+
+	running := session.Running()
+
+	for name, target := range running {
+		if !target.fromFile {
+			continue
+		}
+
+		replacement, ok := wanted[name]
+
+		switch {
+		case !ok:
+			err := showRemoved(target)
+			if err != nil {
+				return err
+			}
+
+			target.prober.Stop()
+		case !sameSettings(target, replacement):
+			err := showRestarted(target)
+			if err != nil {
+				return err
+			}
+
+			err = session.Restart(target, replacement)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, target := range targets {
+		_, ok := running[target.Name]
+		if ok {
+			continue
+		}
+
+		err := session.Start(target)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"net"
+	"strconv"
+)
+
+// newTCPProber times a full TCP connect to the target, i.e. the SYN/SYN-ACK exchange,
+// for hosts that filter ICMP. The connection is closed as soon as it is established.
+func newTCPProber(target *Target) (*RequestProber, error) {
+	ipaddr, err := target.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := target.Binding.dialer("tcp")
+
+	prober := newRequestProber(target, ipaddr, func(ctx context.Context, ipaddr *net.IPAddr) ([]Detail, error) {
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ipaddr.String(), strconv.Itoa(target.Port)))
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, conn.Close()
+	})
+
+	return prober, nil
+}
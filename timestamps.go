@@ -0,0 +1,91 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const DATE string = "2006-01-02 15:04:05.000 MST"
+
+const (
+	TimestampDefault string = "default"
+	TimestampRFC3339 string = "rfc3339"
+	TimestampUnix    string = "unix"
+	TimestampElapsed string = "elapsed"
+)
+
+// Formats tried, in order, when detecting the timestamps used by an existing log
+var knownTimestampFormats = []string{TimestampDefault, TimestampRFC3339, TimestampUnix, TimestampElapsed}
+
+var unixTimestamp = regexp.MustCompile(`^\d+\.\d+$`)
+
+// The reference point for elapsed timestamps
+var startedAt time.Time
+
+func validTimestampFormat(format string) bool {
+	switch format {
+	case TimestampDefault, TimestampRFC3339, TimestampUnix, TimestampElapsed:
+		return true
+	}
+
+	// A custom layout must contain at least one element Go will substitute
+	return time.Unix(0, 0).UTC().Format(format) != format
+}
+
+func formatTimestamp(t time.Time) string {
+	switch timestampFormat {
+	case TimestampDefault:
+		return t.Format(DATE)
+	case TimestampRFC3339:
+		return t.Format(time.RFC3339Nano)
+	case TimestampUnix:
+		return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/1000)
+	case TimestampElapsed:
+		return "+" + t.Sub(startedAt).Round(time.Millisecond).String()
+	default:
+		return t.Format(timestampFormat)
+	}
+}
+
+func parseTimestamp(text, format string) error {
+	var err error
+
+	switch format {
+	case TimestampDefault:
+		_, err = time.Parse(DATE, text)
+	case TimestampRFC3339:
+		_, err = time.Parse(time.RFC3339Nano, text)
+	case TimestampUnix:
+		if !unixTimestamp.MatchString(text) {
+			err = fmt.Errorf("invalid unix timestamp %q", text)
+		}
+	case TimestampElapsed:
+		elapsed, found := strings.CutPrefix(text, "+")
+		if !found {
+			return fmt.Errorf("invalid elapsed timestamp %q", text)
+		}
+
+		_, err = time.ParseDuration(elapsed)
+	default:
+		_, err = time.Parse(format, text)
+	}
+
+	return err
+}
+
+// detectTimestampFormat returns the first known format that text parses as.
+func detectTimestampFormat(text string) (string, error) {
+	for _, format := range knownTimestampFormats {
+		if parseTimestamp(text, format) == nil {
+			return format, nil
+		}
+	}
+
+	return "", fmt.Errorf("unrecognized timestamp %q; use --timestamp-format to specify a custom layout", text)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// newTLSConfig returns the client configuration shared by HTTPS and TLS probes.
+func newTLSConfig(serverName string) *tls.Config {
+	return &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: insecure,
+	}
+}
+
+// expiryDays returns the whole days left until t, rounding down, so an expired certificate reports a negative count.
+func expiryDays(t time.Time) int {
+	return int(time.Until(t).Hours() / 24)
+}
+
+// newTLSProber times a full TLS handshake against the target, after its TCP connect.
+// Each reply notes the negotiated protocol and cipher, and the subject of the leaf certificate
+// and how long it has left, which is flagged once it drops below --cert-warn-days.
+func newTLSProber(target *Target) (*RequestProber, error) {
+	ipaddr, err := target.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	serverName := target.ServerName
+	if serverName == "" {
+		serverName = target.Host
+	}
+
+	config := newTLSConfig(serverName)
+
+	dialer := target.Binding.dialer("tcp")
+
+	prober := newRequestProber(target, ipaddr, func(ctx context.Context, ipaddr *net.IPAddr) ([]Detail, error) {
+		start := time.Now()
+
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ipaddr.String(), strconv.Itoa(target.Port)))
+		if err != nil {
+			return nil, err
+		}
+		defer conn.Close()
+
+		connected := time.Now()
+
+		client := tls.Client(conn, config)
+
+		err = client.HandshakeContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		state := client.ConnectionState()
+
+		details := []Detail{
+			{Key: "connect", Value: connected.Sub(start)},
+			{Key: "handshake", Value: time.Since(connected)},
+			{Key: "protocol", Value: strings.Replace(tls.VersionName(state.Version), "TLS ", "TLSv", 1)},
+			{Key: "cipher", Value: tls.CipherSuiteName(state.CipherSuite)},
+		}
+
+		if len(state.PeerCertificates) > 0 {
+			leaf := state.PeerCertificates[0]
+
+			if leaf.Subject.CommonName != "" {
+				details = append(details, Detail{Key: "subject", Value: leaf.Subject.CommonName})
+			}
+
+			days := expiryDays(leaf.NotAfter)
+
+			details = append(details, Detail{Key: "expiry_days", Value: days, Warn: days < certWarnDays})
+		}
+
+		return details, nil
+	})
+
+	return prober, nil
+}
@@ -0,0 +1,209 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestCertificate returns a self-signed certificate for 127.0.0.1 that expires in validFor.
+func newTestCertificate(t *testing.T, subject string, validFor time.Duration) tls.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: subject},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validFor),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:     []string{subject},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// listenTLS serves TLS 1.2 handshakes with a fixed cipher suite on a local port, until the test ends.
+func listenTLS(t *testing.T, cert tls.Certificate) int {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				_ = conn.(*tls.Conn).Handshake()
+			}()
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// probeTLS runs a single probe of a tls target on port, as --sni subject.
+func probeTLS(t *testing.T, port int, subject string) ([]Detail, error) {
+	t.Helper()
+
+	target := &Target{
+		Host:       "127.0.0.1",
+		Name:       "127.0.0.1",
+		Protocol:   ProtocolTLS,
+		Port:       port,
+		ServerName: subject,
+	}
+
+	prober, err := newTLSProber(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return prober.probe(ctx, prober.IPAddr())
+}
+
+func detailsByKey(details []Detail) map[string]Detail {
+	byKey := make(map[string]Detail, len(details))
+
+	for _, detail := range details {
+		byKey[detail.Key] = detail
+	}
+
+	return byKey
+}
+
+func TestTLSProbe(t *testing.T) {
+	defer func(i bool, days int) { insecure, certWarnDays = i, days }(insecure, certWarnDays)
+
+	// The test certificates are self-signed
+	insecure = true
+	certWarnDays = 14
+
+	tests := []struct {
+		name     string
+		validFor time.Duration
+		days     int
+		warn     bool
+	}{
+		{"far from expiry", 90*24*time.Hour + time.Hour, 90, false},
+		{"near expiry", 3*24*time.Hour + time.Hour, 3, true},
+		{"expired", -2*24*time.Hour + time.Hour, -1, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			port := listenTLS(t, newTestCertificate(t, "pinglog.test", test.validFor))
+
+			details, err := probeTLS(t, port, "pinglog.test")
+			if err != nil {
+				t.Fatalf("handshake failed: %v", err)
+			}
+
+			byKey := detailsByKey(details)
+
+			for _, key := range []string{"connect", "handshake"} {
+				duration, ok := byKey[key].Value.(time.Duration)
+				if !ok || duration <= 0 {
+					t.Errorf("%s = %v, want a positive duration", key, byKey[key].Value)
+				}
+			}
+
+			want := map[string]any{
+				"protocol":    "TLSv1.2",
+				"cipher":      "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+				"subject":     "pinglog.test",
+				"expiry_days": test.days,
+			}
+
+			for key, value := range want {
+				if byKey[key].Value != value {
+					t.Errorf("%s = %v, want %v", key, byKey[key].Value, value)
+				}
+			}
+
+			if byKey["expiry_days"].Warn != test.warn {
+				t.Errorf("expiry_days warn = %t, want %t", byKey["expiry_days"].Warn, test.warn)
+			}
+		})
+	}
+}
+
+func TestTLSProbeFailures(t *testing.T) {
+	defer func(i bool) { insecure = i }(insecure)
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		insecure = false
+
+		port := listenTLS(t, newTestCertificate(t, "pinglog.test", 90*24*time.Hour))
+
+		_, err := probeTLS(t, port, "pinglog.test")
+		if err == nil {
+			t.Fatal("handshake with a self-signed certificate succeeded without --insecure")
+		}
+	})
+
+	t.Run("not tls", func(t *testing.T) {
+		insecure = true
+
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer listener.Close()
+
+		go func() {
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+
+				conn.Close()
+			}
+		}()
+
+		_, err = probeTLS(t, listener.Addr().(*net.TCPAddr).Port, "pinglog.test")
+		if err == nil {
+			t.Fatal("handshake with a plain tcp server succeeded")
+		}
+	})
+}
@@ -0,0 +1,549 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"golang.org/x/net/icmp"
+	netipv4 "golang.org/x/net/ipv4"
+	netipv6 "golang.org/x/net/ipv6"
+)
+
+// Payload carried by every trace probe, matching the default ping size
+const traceSize int = 56
+
+// Hop holds the running statistics for one TTL along the path.
+type Hop struct {
+	ttl  int
+	addr string
+	lost int
+	rttStats
+}
+
+// loss only counts probes whose reply timeout has passed, so those still in flight are not shown as lost.
+func (h *Hop) loss() float64 {
+	if h.recv+h.lost == 0 {
+		return 0
+	}
+
+	return float64(h.lost) / float64(h.recv+h.lost) * 100
+}
+
+// An echo request still waiting on an answer from its hop
+type traceProbe struct {
+	hop  *Hop
+	seq  int
+	sent time.Time
+}
+
+// Trace discovers the path to a host by sending echo requests with increasing TTLs,
+// then keeps probing every hop along it once per interval.
+type Trace struct {
+	host   string
+	dst    *net.IPAddr
+	v6     bool
+	conn   net.PacketConn
+	id     int
+	out    *Output
+	colors *Colors
+	live   bool
+
+	mu       sync.Mutex
+	seq      int
+	hops     []*Hop
+	pending  map[int]*traceProbe
+	reached  int
+	distance int
+	closed   bool
+}
+
+func newTrace(host string) (*Trace, error) {
+	dst, err := net.ResolveIPAddr(ipNetwork(), host)
+	if err != nil {
+		return nil, err
+	}
+
+	v6 := dst.IP.To4() == nil
+
+	// Time Exceeded messages are only ever delivered to raw sockets
+	privileged, err := icmpPrivileged(v6)
+	if err != nil {
+		return nil, err
+	}
+
+	if !privileged {
+		return nil, ErrTraceUnprivileged
+	}
+
+	network, address := "ip4:icmp", "0.0.0.0"
+	if v6 {
+		network, address = "ip6:ipv6-icmp", "::"
+	}
+
+	if sourceAddress() != "" {
+		address = sourceAddress()
+	}
+
+	listener := net.ListenConfig{Control: globalBinding().control()}
+
+	conn, err := listener.ListenPacket(context.Background(), network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := newOutput(host, false)
+	if err != nil {
+		conn.Close()
+
+		return nil, err
+	}
+
+	t := &Trace{
+		host:    host,
+		dst:     dst,
+		v6:      v6,
+		conn:    conn,
+		id:      rand.IntN(1 << 16),
+		out:     out,
+		colors:  newColors(0),
+		live:    isatty.IsTerminal(os.Stdout.Fd()),
+		pending: make(map[int]*traceProbe),
+	}
+
+	for ttl := 1; ttl <= maxHops; ttl++ {
+		t.hops = append(t.hops, &Hop{ttl: ttl})
+	}
+
+	return t, nil
+}
+
+// logf writes a timestamped line for hop, or for the trace as a whole when hop is nil.
+// While the live table has the terminal, lines only go to the log file.
+func (t *Trace) logf(hop *Hop, format string, a ...any) error {
+	var prefix string
+
+	if timestamp {
+		prefix += t.colors.Grey.Sprint(formatTimestamp(time.Now())) + " | "
+	}
+
+	if hop != nil {
+		prefix += t.colors.Label.Sprintf("hop %d", hop.ttl) + " | "
+	}
+
+	if t.live {
+		return t.out.Logf("%s%s", prefix, fmt.Sprintf(format, a...))
+	}
+
+	return t.out.Printf("%s%s", prefix, fmt.Sprintf(format, a...))
+}
+
+// limit returns how many hops are currently worth probing: up to the destination once found.
+func (t *Trace) limit() int {
+	if t.reached > 0 {
+		return t.reached
+	}
+
+	return len(t.hops)
+}
+
+// announce logs where the destination was found, or that it has moved, since the last round.
+func (t *Trace) announce() error {
+	if t.reached == 0 || t.reached == t.distance {
+		return nil
+	}
+
+	previous := t.distance
+	t.distance = t.reached
+
+	if previous == 0 {
+		return t.logf(nil, "Destination reached at hop %d.\n", t.reached)
+	}
+
+	return t.logf(nil, "%s", t.colors.Red.Sprintf("Destination moved from hop %d to hop %d.\n", previous, t.reached))
+}
+
+func (t *Trace) send() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := t.announce()
+	if err != nil {
+		return err
+	}
+
+	var echo icmp.Type = netipv4.ICMPTypeEcho
+	if t.v6 {
+		echo = netipv6.ICMPTypeEchoRequest
+	}
+
+	for _, hop := range t.hops[:t.limit()] {
+		seq := t.seq % int(sequenceSpace)
+		t.seq++
+
+		msg := icmp.Message{
+			Type: echo,
+			Body: &icmp.Echo{
+				ID:   t.id,
+				Seq:  seq,
+				Data: make([]byte, traceSize),
+			},
+		}
+
+		packet, err := msg.Marshal(nil)
+		if err != nil {
+			return err
+		}
+
+		if t.v6 {
+			err = netipv6.NewPacketConn(t.conn).SetHopLimit(hop.ttl)
+		} else {
+			err = netipv4.NewPacketConn(t.conn).SetTTL(hop.ttl)
+		}
+		if err != nil {
+			return err
+		}
+
+		t.pending[seq] = &traceProbe{hop: hop, seq: hop.sent, sent: time.Now()}
+		hop.sent++
+
+		_, err = t.conn.WriteTo(packet, t.dst)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expire declares lost every probe sent more than the reply timeout before now.
+func (t *Trace) expire(now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for seq, probe := range t.pending {
+		if now.Sub(probe.sent) < replyTimeout {
+			continue
+		}
+
+		delete(t.pending, seq)
+
+		probe.hop.lost++
+
+		// Routers that never answer at all are just not worth hearing about
+		if probe.hop.addr == "" || probe.hop.ttl > t.limit() || !dropped {
+			continue
+		}
+
+		err := t.logf(probe.hop, "%s", t.colors.Red.Sprintf("Packet %d lost.\n", probe.seq))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// quoted digs the ID and sequence number of our echo request back out of the
+// original datagram carried by an ICMP error.
+func (t *Trace) quoted(data []byte) (int, int, bool) {
+	header := 40
+	if !t.v6 {
+		if len(data) < 1 {
+			return 0, 0, false
+		}
+
+		header = int(data[0]&0x0f) * 4
+	}
+
+	if len(data) < header+8 {
+		return 0, 0, false
+	}
+
+	echo := data[header:]
+
+	if (!t.v6 && echo[0] != byte(netipv4.ICMPTypeEcho)) || (t.v6 && echo[0] != byte(netipv6.ICMPTypeEchoRequest)) {
+		return 0, 0, false
+	}
+
+	return int(binary.BigEndian.Uint16(echo[4:6])), int(binary.BigEndian.Uint16(echo[6:8])), true
+}
+
+func (t *Trace) receive() error {
+	proto := 1
+	if t.v6 {
+		proto = 58
+	}
+
+	buf := make([]byte, 1500)
+
+	for {
+		n, peer, err := t.conn.ReadFrom(buf)
+		if err != nil {
+			t.mu.Lock()
+			closed := t.closed
+			t.mu.Unlock()
+
+			if closed {
+				return nil
+			}
+
+			return err
+		}
+
+		received := time.Now()
+
+		msg, err := icmp.ParseMessage(proto, buf[:n])
+		if err != nil {
+			continue
+		}
+
+		var id, seq int
+		var ok, final bool
+
+		switch body := msg.Body.(type) {
+		case *icmp.Echo:
+			if msg.Type != netipv4.ICMPTypeEchoReply && msg.Type != netipv6.ICMPTypeEchoReply {
+				continue
+			}
+
+			id, seq, ok = body.ID, body.Seq, true
+			final = true
+		case *icmp.TimeExceeded:
+			id, seq, ok = t.quoted(body.Data)
+		case *icmp.DstUnreach:
+			// Nothing further along will answer either
+			id, seq, ok = t.quoted(body.Data)
+			final = true
+		}
+
+		if !ok || id != t.id {
+			continue
+		}
+
+		err = t.answered(seq, peer.String(), received, final)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// answered records a reply to probe seq from addr; final replies come from the end of the path.
+func (t *Trace) answered(seq int, addr string, received time.Time, final bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	probe, ok := t.pending[seq]
+	if !ok {
+		return nil
+	}
+
+	delete(t.pending, seq)
+
+	hop := probe.hop
+	rtt := received.Sub(probe.sent)
+
+	// Probes sent past the destination before we knew where it was
+	if t.reached > 0 && hop.ttl > t.reached {
+		return nil
+	}
+
+	switch {
+	case hop.addr == "":
+		err := t.logf(hop, "Discovered %s.\n", t.colors.Blue.Sprint(addr))
+		if err != nil {
+			return err
+		}
+	case hop.addr != addr:
+		err := t.logf(hop, "%s", t.colors.Red.Sprintf("Path changed: %s => %s.\n", hop.addr, addr))
+		if err != nil {
+			return err
+		}
+	}
+
+	hop.addr = addr
+	hop.record(rtt)
+
+	switch {
+	case final && (t.reached == 0 || hop.ttl < t.reached):
+		// Announced with the next round, once every reply to this one has had its say
+		t.reached = hop.ttl
+	case !final && hop.ttl == t.reached:
+		// The path has grown, so look further out again until the destination answers
+		t.reached = 0
+	}
+
+	return t.logf(hop, "reply from %s: hop_seq=%s time=%s\n",
+		t.colors.Blue.Sprint(addr),
+		t.colors.Blue.Sprintf("%d", probe.seq),
+		t.colors.Blue.Sprint(rtt.Round(time.Microsecond)))
+}
+
+// table renders the statistics for every hop up to the destination.
+func (t *Trace) table() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var s strings.Builder
+
+	w := tabwriter.NewWriter(&s, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintln(w, "HOP\tHOST\tLOSS\tSENT\tLAST\tAVG\tBEST\tWORST\tJITTER\t")
+
+	for _, hop := range t.hops[:t.limit()] {
+		addr := hop.addr
+		if addr == "" {
+			addr = "???"
+		}
+
+		fmt.Fprintf(w, "%d\t%s\t%.1f%%\t%d\t%s\t%s\t%s\t%s\t%s\t\n",
+			hop.ttl,
+			addr,
+			hop.loss(),
+			hop.sent,
+			hop.last.Round(time.Microsecond),
+			hop.avg().Round(time.Microsecond),
+			hop.best.Round(time.Microsecond),
+			hop.worst.Round(time.Microsecond),
+			hop.jitter.Round(time.Microsecond))
+	}
+
+	w.Flush()
+
+	return s.String()
+}
+
+func (t *Trace) header() string {
+	return fmt.Sprintf("TRACE %s (%s)%s, up to %d hops.\n", t.host, t.dst.IP, globalBinding().banner(t.colors), maxHops)
+}
+
+// redraw replaces the terminal contents with the current table.
+func (t *Trace) redraw() {
+	table := t.table()
+
+	printMu.Lock()
+	defer printMu.Unlock()
+
+	fmt.Printf("\033[H\033[2J%s%s elapsed.\n\n%s", t.header(), time.Since(startedAt).Round(time.Second), table)
+}
+
+func (t *Trace) finish() error {
+	t.mu.Lock()
+	err := t.announce()
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if t.live {
+		t.redraw()
+
+		return t.out.Logf("\n%s", t.table())
+	}
+
+	return t.out.Printf("\n%s", t.table())
+}
+
+func (t *Trace) Close() error {
+	t.mu.Lock()
+	t.closed = true
+	t.mu.Unlock()
+
+	err := t.conn.Close()
+	if err != nil {
+		return err
+	}
+
+	return t.out.Close()
+}
+
+func runTrace(host string) error {
+	err := setTimeZone()
+	if err != nil {
+		return err
+	}
+
+	color.NoColor = !colorize
+
+	t, err := newTrace(host)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+
+	startedAt = time.Now()
+
+	err = t.logf(nil, "%s", t.header())
+	if err != nil {
+		return err
+	}
+
+	failed := make(chan error, 1)
+
+	go func() {
+		failed <- t.receive()
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for cycle := 0; count == 0 || cycle < count; cycle++ {
+		err = t.expire(time.Now())
+		if err != nil {
+			return err
+		}
+
+		err = t.send()
+		if err != nil {
+			return err
+		}
+
+		if t.live {
+			t.redraw()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-interrupt:
+			return t.finish()
+		case <-deadline.C:
+			return t.finish()
+		case err := <-failed:
+			return err
+		}
+	}
+
+	// Give the final round of probes the same chance to be answered as the rest
+	select {
+	case <-time.After(replyTimeout):
+	case <-interrupt:
+	}
+
+	err = t.expire(time.Now().Add(replyTimeout))
+	if err != nil {
+		return err
+	}
+
+	return t.finish()
+}
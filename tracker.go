@@ -0,0 +1,277 @@
+/*
+Copyright © 2026 Seednode <seednode@seedno.de>
+*/
+
+package main
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+type ReplyStatus int
+
+const (
+	ReplyOnTime ReplyStatus = iota
+	ReplyReordered
+	ReplyLate
+)
+
+type Counts struct {
+	Sent      int
+	Lost      int
+	Late      int
+	Reordered int
+}
+
+// ICMP sequence numbers are 16 bits wide, and pro-bing wraps them back to zero after 65535
+const sequenceSpace uint64 = 1 << 16
+
+type inflight struct {
+	timer    *time.Timer
+	deadline time.Time
+}
+
+// Tracker follows every sequence number from the moment it is sent, and declares
+// it lost as soon as its reply deadline passes without a response.
+//
+// Once a newer sequence has been answered, any older packets still outstanding
+// are only given the reorder window to turn up before being declared lost.
+//
+// Sequence numbers on the wire are extended to 64 bits, so a run can wrap the
+// 16-bit ICMP counter any number of times without confusing the bookkeeping.
+type Tracker struct {
+	mu            sync.Mutex
+	deadline      time.Duration
+	reorderWindow time.Duration
+	pending       map[uint64]*inflight
+	expired       map[uint64]struct{}
+	next          uint64
+	highest       uint64
+	answered      bool
+	counts        Counts
+	stopped       bool
+	onLost        func(seq uint64, reason string)
+}
+
+func newTracker(deadline, reorderWindow time.Duration, onLost func(seq uint64, reason string)) *Tracker {
+	return &Tracker{
+		deadline:      deadline,
+		reorderWindow: reorderWindow,
+		pending:       make(map[uint64]*inflight),
+		expired:       make(map[uint64]struct{}),
+		onLost:        onLost,
+	}
+}
+
+// extend maps a 16-bit wire sequence onto the most recent extended sequence
+// already sent that shares its low bits.
+func (t *Tracker) extend(raw int) uint64 {
+	low := uint64(raw) % sequenceSpace
+
+	if t.next == 0 {
+		return low
+	}
+
+	last := t.next - 1
+
+	seq := last - last%sequenceSpace + low
+	if seq > last && seq >= sequenceSpace {
+		seq -= sequenceSpace
+	}
+
+	return seq
+}
+
+// Extend returns the extended sequence for a 16-bit sequence seen on the wire.
+func (t *Tracker) Extend(raw int) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.extend(raw)
+}
+
+// Sent starts the reply deadline for a packet, and returns its extended sequence.
+func (t *Tracker) Sent(raw int) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	low := uint64(raw) % sequenceSpace
+
+	seq := t.next - t.next%sequenceSpace + low
+	if seq < t.next {
+		seq += sequenceSpace
+	}
+
+	t.next = seq + 1
+
+	// A reply this old can no longer be told apart from the current one on the wire
+	if seq >= sequenceSpace {
+		delete(t.expired, seq-sequenceSpace)
+	}
+
+	if t.stopped {
+		return seq
+	}
+
+	t.counts.Sent++
+
+	t.pending[seq] = &inflight{
+		timer: time.AfterFunc(t.deadline, func() {
+			t.expire(seq)
+		}),
+		deadline: time.Now().Add(t.deadline),
+	}
+
+	return seq
+}
+
+func (t *Tracker) expire(seq uint64) {
+	t.mu.Lock()
+
+	_, ok := t.pending[seq]
+	if !ok || t.stopped {
+		t.mu.Unlock()
+
+		return
+	}
+
+	delete(t.pending, seq)
+	t.expired[seq] = struct{}{}
+	t.counts.Lost++
+
+	t.mu.Unlock()
+
+	t.onLost(seq, "")
+}
+
+// Fail declares a packet lost straight away, for probes that know they failed
+// (e.g. a refused TCP connection) before their deadline is up.
+func (t *Tracker) Fail(raw int, reason string) {
+	t.mu.Lock()
+
+	seq := t.extend(raw)
+
+	p, ok := t.pending[seq]
+	if !ok || t.stopped {
+		t.mu.Unlock()
+
+		return
+	}
+
+	p.timer.Stop()
+	delete(t.pending, seq)
+	t.counts.Lost++
+
+	t.mu.Unlock()
+
+	t.onLost(seq, reason)
+}
+
+// shortenOvertaken caps the remaining deadline of every packet older than seq at the reorder window.
+func (t *Tracker) shortenOvertaken(seq uint64) {
+	if t.reorderWindow <= 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(t.reorderWindow)
+
+	for s, p := range t.pending {
+		if s < seq && p.deadline.After(cutoff) {
+			p.timer.Reset(t.reorderWindow)
+			p.deadline = cutoff
+		}
+	}
+}
+
+// Received records a reply, and reports its extended sequence along with whether
+// it arrived in order, out of order, or after it had already been declared lost.
+func (t *Tracker) Received(raw int) (uint64, ReplyStatus) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	seq := t.extend(raw)
+
+	p, ok := t.pending[seq]
+	if ok {
+		p.timer.Stop()
+		delete(t.pending, seq)
+
+		if t.answered && seq < t.highest {
+			t.counts.Reordered++
+
+			return seq, ReplyReordered
+		}
+
+		t.highest = seq
+		t.answered = true
+		t.shortenOvertaken(seq)
+
+		return seq, ReplyOnTime
+	}
+
+	_, ok = t.expired[seq]
+	if ok {
+		delete(t.expired, seq)
+		t.counts.Lost--
+		t.counts.Late++
+
+		return seq, ReplyLate
+	}
+
+	return seq, ReplyOnTime
+}
+
+// Settled reports whether every sent packet has either been answered or declared lost.
+func (t *Tracker) Settled() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.pending) == 0
+}
+
+func (t *Tracker) Counts() Counts {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.counts
+}
+
+// Flush declares every outstanding packet lost without waiting for its deadline.
+func (t *Tracker) Flush() {
+	t.mu.Lock()
+
+	t.stopped = true
+
+	outstanding := make([]uint64, 0, len(t.pending))
+
+	for seq, p := range t.pending {
+		p.timer.Stop()
+		outstanding = append(outstanding, seq)
+		t.expired[seq] = struct{}{}
+		t.counts.Lost++
+	}
+
+	clear(t.pending)
+
+	t.mu.Unlock()
+
+	slices.Sort(outstanding)
+
+	for _, seq := range outstanding {
+		t.onLost(seq, "")
+	}
+}
+
+// Stop cancels all outstanding deadlines without declaring anything lost.
+func (t *Tracker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.stopped = true
+
+	for _, p := range t.pending {
+		p.timer.Stop()
+	}
+}